@@ -0,0 +1,275 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/arcana261/lifeinuk/sliceutils"
+)
+
+const (
+	nearDuplicateOverlap = 0.6
+	entrySeparator       = "---"
+	highlightSeparator   = "\n\n" + entrySeparator + "\n\n"
+)
+
+type duplicate struct {
+	Highlight *Highlight
+	Overlap   float64
+}
+
+// tokenOverlap is the share of the distinct tokens of a that also appear in b,
+// so a new highlight restating part of a longer one still counts as similar.
+func tokenOverlap(a []string, b []string) float64 {
+	as := sliceutils.UniqueSorted(sliceutils.Sort(slices.Clone(a)))
+	bs := sliceutils.UniqueSorted(sliceutils.Sort(slices.Clone(b)))
+	if len(as) == 0 || len(bs) == 0 {
+		return 0
+	}
+	common := 0
+	for _, t := range as {
+		if _, ok := slices.BinarySearch(bs, t); ok {
+			common = common + 1
+		}
+	}
+	return float64(common) / float64(len(as))
+}
+
+func findDuplicates(highlights HighlightDatabase, tokens []string) []duplicate {
+	var result []duplicate
+	for k := range highlights.Highlights {
+		h := &highlights.Highlights[k]
+		existing := sliceutils.MapFunc(h.Tokens, func(id int) string {
+			return highlights.TokenMap[id].Content
+		})
+		overlap := tokenOverlap(tokens, existing)
+		if overlap >= nearDuplicateOverlap {
+			result = append(result, duplicate{Highlight: h, Overlap: overlap})
+		}
+	}
+	slices.SortFunc(result, func(x, y duplicate) int {
+		return -CompareFloat64(x.Overlap, y.Overlap)
+	})
+	return result
+}
+
+func readParagraph() (string, error) {
+	var lines []string
+	var line bytes.Buffer
+	for {
+		buff := make([]byte, 1)
+		n, err := stdin.Read(buff)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", &InputError{Err: err}
+		}
+		if n == 1 {
+			if buff[0] != '\n' {
+				line.WriteByte(buff[0])
+				continue
+			}
+			str := strings.TrimSpace(line.String())
+			line.Reset()
+			if str == "" {
+				if len(lines) > 0 {
+					break
+				}
+				continue
+			}
+			lines = append(lines, str)
+		}
+	}
+	if str := strings.TrimSpace(line.String()); str != "" {
+		lines = append(lines, str)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func appendHighlights(s Store, entries []string) error {
+	deck, err := s.ReadDeck()
+	if err != nil {
+		return err
+	}
+
+	// The deck is split on every "---", so such an entry would come back as
+	// several highlights.
+	for _, entry := range entries {
+		if strings.Contains(entry, entrySeparator) {
+			return fmt.Errorf("highlight %q contains %q, which separates highlights in the deck", entry, entrySeparator)
+		}
+	}
+
+	var buff bytes.Buffer
+	buff.WriteString(strings.TrimRight(deck, " \t\r\n"))
+	for _, entry := range entries {
+		if buff.Len() > 0 {
+			buff.WriteString(highlightSeparator)
+		}
+		buff.WriteString(entry)
+	}
+	buff.WriteString("\n")
+	return s.WriteDeck(buff.String())
+}
+
+func printQuizzedTokens(highlights HighlightDatabase, content string, tokens []ParsedToken) {
+	var lineToPrint bytes.Buffer
+	rs := []rune(content)
+	skip := make(map[string]bool)
+	for _, known := range highlights.TokenMap {
+		skip[known.Content] = known.SkipPuzzle
+	}
+	last := 0
+	skipped := 0
+	for _, t := range tokens {
+		lineToPrint.WriteString(string(rs[last:t.Start]))
+		if skip[t.Content] {
+			skipped = skipped + 1
+			lineToPrint.WriteString(string(rs[t.Start:t.End]))
+		} else {
+			lineToPrint.WriteString(colorYellow)
+			lineToPrint.WriteString(string(rs[t.Start:t.End]))
+			lineToPrint.WriteString(colorNone)
+		}
+		last = t.End
+	}
+	lineToPrint.WriteString(string(rs[last:]))
+	printContent(lineToPrint.String())
+	fmt.Fprintf(stdout, "%d of %d tokens quizzed, %d skipped as too common\n", len(tokens)-skipped, len(tokens), skipped)
+}
+
+// pickNewEntries shows every entry with its quizzed tokens and returns the
+// ones not in the deck yet, ready for appendHighlights. Near duplicates are
+// asked about when interactive and skipped otherwise, unless force is set.
+func pickNewEntries(highlights HighlightDatabase, entries, questions, sources []string, force, interactive bool) ([]string, error) {
+	var added []string
+	var addedIDs []string
+	for k, entry := range entries {
+		if strings.Contains(entryText(entry, questions[k], sources[k]), entrySeparator) {
+			fmt.Fprintf(stdout, "\n%sContains \"%s\", which separates highlights in the deck, skipping:%s\n", colorRed, entrySeparator, colorNone)
+			printContent(entry)
+			continue
+		}
+		entry = fixAlignment(entry, alignmentWidth)
+		tokens := mergePhrases(entry, defaultTokenizer.Tokenize(entry), highlights.Phrases)
+		contents := sliceutils.MapFunc(tokens, func(x ParsedToken) string {
+			return x.Content
+		})
+		id := generateID(contents)
+
+		fmt.Fprintf(stdout, "\n%s%s%s\n", colorBlue, id, colorNone)
+		if highlights.FindHighlight(id) != nil || highlights.Sources[id] != nil || slices.Contains(addedIDs, id) {
+			fmt.Fprintf(stdout, "%sAlready in the deck, skipping:%s\n", colorRed, colorNone)
+			printContent(entry)
+			continue
+		}
+		printQuizzedTokens(highlights, entry, tokens)
+
+		duplicates := findDuplicates(highlights, contents)
+		for _, d := range duplicates {
+			fmt.Fprintf(stdout, "%sSimilar to #%d (%.0f%% token overlap):%s\n", colorRed, d.Highlight.Index+1, d.Overlap*100, colorNone)
+			printContent(d.Highlight.Content)
+		}
+		if len(duplicates) > 0 && !force {
+			if !interactive {
+				fmt.Fprintf(stdout, "Skipped, use -force to add near duplicates\n")
+				continue
+			}
+			var answer string
+			for answer != "y" && answer != "n" {
+				fmt.Fprintf(stdout, "Add anyway? (Y/N) ")
+				key, err := readOne()
+				if err != nil {
+					return nil, err
+				}
+				answer = strings.ToLower(key)
+			}
+			if answer == "n" {
+				continue
+			}
+		}
+
+		added = append(added, entryText(entry, questions[k], sources[k]))
+		addedIDs = append(addedIDs, id)
+	}
+	return added, nil
+}
+
+func cmdAdd(args []string) error {
+	fs := flag.NewFlagSet("add", flag.ContinueOnError)
+	file := fs.String("file", "", "read highlights separated by --- from this file instead of stdin")
+	force := fs.Bool("force", false, "add near duplicates without asking")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	unlock, err := lockData()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	highlights, err := loadHighlights()
+	if err != nil {
+		return err
+	}
+
+	terminal, ok := stdin.(*os.File)
+	interactive := *file == "" && ok && isTerminal(terminal)
+	var text string
+	switch {
+	case *file != "":
+		bs, err := os.ReadFile(*file)
+		if err != nil {
+			return err
+		}
+		text = string(bs)
+	case interactive:
+		fmt.Fprintf(stdout, "Type the new highlight, optionally starting with a \"%s\" line, and finish with an empty line:\n", questionPrefix)
+		text, err = readParagraph()
+		if err != nil {
+			return err
+		}
+	default:
+		bs, err := io.ReadAll(stdin)
+		if err != nil {
+			return err
+		}
+		text = string(bs)
+	}
+
+	entries, questions, sources := splitQuestions(sliceutils.Remove(sliceutils.TrimSpace(strings.Split(text, entrySeparator)), ""))
+	if len(entries) == 0 {
+		return fmt.Errorf("nothing to add")
+	}
+
+	added, err := pickNewEntries(highlights, entries, questions, sources, *force, interactive)
+	if err != nil {
+		return err
+	}
+	if len(added) == 0 {
+		fmt.Fprintf(stdout, "\nNothing added\n")
+		return nil
+	}
+	unlockDeck, err := lockDeck()
+	if err != nil {
+		return err
+	}
+	defer unlockDeck()
+	if err := appendHighlights(store, added); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "\nAdded %d of %d highlights\n", len(added), len(entries))
+	return nil
+}
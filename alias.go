@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/arcana261/lifeinuk/sliceutils"
+)
+
+// readAliases reads the "OLD NEW" lines recorded whenever an edit gave a
+// highlight a new ID, so every profile can move its score to the new ID the
+// next time it loads, even one that was studying while the edit happened.
+func readAliases(fname string) (map[string]string, error) {
+	result := make(map[string]string)
+	if !fileExists(fname) {
+		return result, nil
+	}
+	b, err := os.ReadFile(fname)
+	if err != nil {
+		return nil, err
+	}
+	for index, line := range sliceutils.TrimSpace(strings.Split(string(b), "\n")) {
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		part := strings.Fields(line)
+		if len(part) != 2 {
+			return nil, fmt.Errorf("%s:%d: malformed alias %q", fname, index+1, line)
+		}
+		// The new ID is live again, e.g. after an edit was undone by hand.
+		delete(result, part[1])
+		result[part[0]] = part[1]
+	}
+	return result, nil
+}
+
+func appendAlias(fname string, oldID string, newID string) error {
+	if oldID == newID {
+		return nil
+	}
+	f, err := os.OpenFile(fname, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return &WriteError{Path: fname, Err: err}
+	}
+	_, err = fmt.Fprintf(f, "%s %s\n", oldID, newID)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return &WriteError{Path: fname, Err: err}
+	}
+	return nil
+}
+
+// resolveAlias follows the renames of id, or of the paragraph of a sub-card
+// ID, to the current ID.
+func resolveAlias(aliases map[string]string, id string) string {
+	base, sub, isSub := strings.Cut(id, "#")
+	for steps := 0; steps < len(aliases); steps++ {
+		next, ok := aliases[base]
+		if !ok {
+			break
+		}
+		base = next
+	}
+	if isSub {
+		return base + "#" + sub
+	}
+	return base
+}
+
+// resolveScores moves scores kept under old IDs to the current ones. A score
+// that meets one already under the new ID is merged into it.
+func resolveScores(scores map[string]Score, aliases map[string]string) map[string]Score {
+	if len(aliases) == 0 {
+		return scores
+	}
+	result := make(map[string]Score)
+	for id, score := range scores {
+		if resolveAlias(aliases, id) == id {
+			result[id] = score
+		}
+	}
+	for id, score := range scores {
+		current := resolveAlias(aliases, id)
+		if current == id {
+			continue
+		}
+		if existing, ok := result[current]; ok {
+			result[current] = mergeScore(Score{}, existing, score)
+		} else {
+			result[current] = score
+		}
+	}
+	return result
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/arcana261/lifeinuk/sliceutils"
+)
+
+const (
+	flagSuspended    = "suspended"
+	flagStarred      = "starred"
+	flagBuriedPrefix = "buried:"
+
+	starredChance = 0.5
+)
+
+func (s Score) BuriedUntil() string {
+	for _, f := range s.Flags {
+		if strings.HasPrefix(f, flagBuriedPrefix) {
+			return strings.TrimPrefix(f, flagBuriedPrefix)
+		}
+	}
+	return ""
+}
+
+func (s Score) Available(today string) bool {
+	return !s.HasFlag(flagSuspended) && s.BuriedUntil() <= today
+}
+
+func (s *Score) Bury(until string) {
+	s.Flags = sliceutils.RemoveFunc(s.Flags, func(f string) bool {
+		return strings.HasPrefix(f, flagBuriedPrefix)
+	})
+	s.SetFlag(flagBuriedPrefix + until)
+}
+
+// buriedBefore matches bury flags that have expired by today, so they are
+// dropped when scores are loaded.
+func buriedBefore(today string) func(string) bool {
+	return func(f string) bool {
+		return strings.HasPrefix(f, flagBuriedPrefix) && strings.TrimPrefix(f, flagBuriedPrefix) <= today
+	}
+}
+
+func flagPrompt(highlights HighlightDatabase, h *Highlight) error {
+	for {
+		star := "Star"
+		if h.Score.HasFlag(flagStarred) {
+			star = "Unstar"
+		}
+
+		fmt.Fprintf(stdout, "  S. Suspend\n")
+		fmt.Fprintf(stdout, "  B. Bury until tomorrow\n")
+		fmt.Fprintf(stdout, "  *. %s\n", star)
+		fmt.Fprintf(stdout, "  E. Edit\n")
+		fmt.Fprintf(stdout, "  C. Continue\n")
+		fmt.Fprintf(stdout, "\n")
+
+		key, err := readOne()
+		if err != nil {
+			return err
+		}
+		switch strings.ToLower(key) {
+		case "s":
+			h.Score.SetFlag(flagSuspended)
+			fmt.Fprintf(stdout, "%sSUSPENDED%s\n", colorYellow, colorNone)
+		case "b":
+			h.Score.Bury(time.Now().AddDate(0, 0, 1).Format(dayFormat))
+			fmt.Fprintf(stdout, "%sBURIED UNTIL TOMORROW%s\n", colorYellow, colorNone)
+		case "*":
+			if h.Score.HasFlag(flagStarred) {
+				h.Score.ClearFlag(flagStarred)
+				fmt.Fprintf(stdout, "%sUNSTARRED%s\n", colorYellow, colorNone)
+			} else {
+				h.Score.SetFlag(flagStarred)
+				fmt.Fprintf(stdout, "%sSTARRED%s\n", colorYellow, colorNone)
+			}
+		case "e":
+			_, err := editHighlight(highlights, h)
+			return err
+		case "c":
+			return nil
+		default:
+			continue
+		}
+
+		return saveScores(highlights)
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/arcana261/lifeinuk/sliceutils"
+)
+
+const (
+	checkpointFile = "checkpoint.txt"
+)
+
+type fillProgress struct {
+	ID             string
+	Index          int
+	LastIndex      int
+	CorrectAnswers int
+	WrongAnswers   int
+	Credit         float64
+	Hints          int
+	PreviousWrongs []int
+}
+
+// fillFirstToken is the first token the fill game quizzes, the ones before
+// it are shown as the cue.
+const fillFirstToken = 2
+
+func newFillProgress(h *Highlight) fillProgress {
+	return fillProgress{
+		ID:        h.ID,
+		Index:     fillFirstToken,
+		LastIndex: -1,
+	}
+}
+
+func (p fillProgress) Clone() fillProgress {
+	p.PreviousWrongs = sliceutils.Clone(p.PreviousWrongs)
+	return p
+}
+
+// saveCheckpoint keeps only the position within the card; previous wrong
+// choices are token IDs, which are not stable once the deck is edited.
+func saveCheckpoint(fname string, p fillProgress) error {
+	line := fmt.Sprintf("%s %d %d %d %d %f %d\n", p.ID, p.Index, p.LastIndex, p.CorrectAnswers, p.WrongAnswers, p.Credit, p.Hints)
+	err := os.WriteFile(fname, []byte(line), 0644)
+	if err != nil {
+		return &WriteError{Path: fname, Err: err}
+	}
+	return nil
+}
+
+func loadCheckpoint(fname string) (fillProgress, bool) {
+	if !fileExists(fname) {
+		return fillProgress{}, false
+	}
+
+	b, err := os.ReadFile(fname)
+	if err != nil {
+		return fillProgress{}, false
+	}
+
+	var p fillProgress
+	n, err := fmt.Sscanf(strings.TrimSpace(string(b)), "%s %d %d %d %d %f %d", &p.ID, &p.Index, &p.LastIndex, &p.CorrectAnswers, &p.WrongAnswers, &p.Credit, &p.Hints)
+	if n != 7 || err != nil {
+		return fillProgress{}, false
+	}
+	return p, true
+}
+
+func removeCheckpoint(fname string) error {
+	if !fileExists(fname) {
+		return nil
+	}
+	if err := os.Remove(fname); err != nil {
+		return &WriteError{Path: fname, Err: err}
+	}
+	return nil
+}
+
+func resumeCheckpoint(highlights HighlightDatabase) error {
+	p, ok := loadCheckpoint(profilePath(*profile, checkpointFile))
+	if !ok {
+		return nil
+	}
+	h := highlights.FindHighlight(p.ID)
+	if h == nil {
+		return removeCheckpoint(profilePath(*profile, checkpointFile))
+	}
+	// LastIndex is -1 until the first answer and always before Index.
+	if p.Index < fillFirstToken || p.Index >= len(h.Tokens) || p.LastIndex < -1 || p.LastIndex >= p.Index {
+		fmt.Fprintf(stdout, "%sThe unfinished card was saved at an invalid position, it is discarded%s\n", colorRed, colorNone)
+		return removeCheckpoint(profilePath(*profile, checkpointFile))
+	}
+
+	for {
+		printContent(h.Content)
+		fmt.Fprintf(stdout, "An unfinished card was found, resume it?\n\n")
+		fmt.Fprintf(stdout, "  Y. Yes\n")
+		fmt.Fprintf(stdout, "  N. No\n")
+		fmt.Fprintf(stdout, "\n")
+
+		key, err := readOne()
+		if err != nil {
+			return err
+		}
+		switch strings.ToLower(key) {
+		case "y":
+			return playFillCard(highlights, h, p)
+		case "n":
+			return removeCheckpoint(profilePath(*profile, checkpointFile))
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+)
+
+func runCommand(args []string) error {
+	switch args[0] {
+	case "tokenize":
+		return cmdTokenize(args[1:])
+	case "readiness":
+		return cmdReadiness(args[1:])
+	case "import":
+		return cmdImport(args[1:])
+	case "leeches":
+		return cmdLeeches(args[1:])
+	case "add":
+		return cmdAdd(args[1:])
+	case "export":
+		return cmdExport(args[1:])
+	case "merge-scores":
+		return cmdMergeScores(args[1:])
+	case "profiles":
+		return cmdProfiles(args[1:])
+	case "sync":
+		return cmdSync(args[1:])
+	case "sync-server":
+		return cmdSyncServer(args[1:])
+	case "worksheet":
+		return cmdWorksheet(args[1:])
+	case "script":
+		return cmdScript(args[1:])
+	default:
+		return fmt.Errorf("unknown command: %s", args[0])
+	}
+}
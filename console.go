@@ -0,0 +1,16 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"os"
+)
+
+// All games read keypresses from stdin and print to stdout, so a session can
+// be driven by something other than the terminal.
+var (
+	stdin  io.Reader = os.Stdin
+	stdout io.Writer = os.Stdout
+)
+
+var errEndOfInput = errors.New("end of input")
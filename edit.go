@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+const (
+	defaultEditor = "vi"
+)
+
+func runEditor(text string) (string, error) {
+	f, err := os.CreateTemp("", "lifeinuk-*.txt")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.WriteString(text); err != nil {
+		f.Close()
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+
+	editor := strings.Fields(os.Getenv("EDITOR"))
+	if len(editor) == 0 {
+		editor = []string{defaultEditor}
+	}
+	cmd := exec.Command(editor[0], append(editor[1:], f.Name())...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("running %s: %w", editor[0], err)
+	}
+
+	b, err := os.ReadFile(f.Name())
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// editHighlight opens h in $EDITOR and applies the result to the database in
+// place. It reports whether the highlight changed; problems with the editor
+// are only printed, the error is for saving the result.
+func editHighlight(highlights HighlightDatabase, h *Highlight) (bool, error) {
+	text := h.Content
+	if h.Parent == "" {
+		text = entryText(h.Content, h.Question, h.Source)
+	}
+
+	edited, err := runEditor(text + "\n")
+	if err != nil {
+		fmt.Fprintf(stdout, "%s%s%s\n", colorRed, err, colorNone)
+		return false, nil
+	}
+	if strings.TrimSpace(edited) == strings.TrimSpace(text) {
+		return false, nil
+	}
+
+	if strings.Contains(edited, entrySeparator) {
+		fmt.Fprintf(stdout, "%sThe edited highlight contains \"%s\", which separates highlights, keeping the original%s\n", colorRed, entrySeparator, colorNone)
+		return false, nil
+	}
+	contents, questions, sources := splitQuestions([]string{strings.TrimSpace(edited)})
+	if len(contents) == 0 {
+		fmt.Fprintf(stdout, "%sThe edited highlight is empty, keeping the original%s\n", colorRed, colorNone)
+		return false, nil
+	}
+
+	unlock, err := lockDeck()
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+	if changed, err := highlights.DeckChanged(store); err != nil || changed {
+		if changed {
+			fmt.Fprintf(stdout, "%sThe deck was changed by another session, restart to edit this highlight%s\n", colorRed, colorNone)
+		}
+		return false, err
+	}
+
+	oldID := h.ID
+	oldParent := h.Parent
+	if h.Parent != "" {
+		highlights.setSubContent(h, contents[0], *splitTokens)
+	} else {
+		highlights.SetContent(h, fixAlignment(contents[0], alignmentWidth), questions[0])
+		h.Source = sources[0]
+	}
+	for i := range highlights.Highlights {
+		if &highlights.Highlights[i] != h && highlights.Highlights[i].ID == h.ID {
+			fmt.Fprintf(stdout, "DUPLICAT ID FOUND!:\n========\n%s\n========\n", h.Content)
+		}
+	}
+
+	if err := WriteHighlights(highlights, store); err != nil {
+		return true, err
+	}
+	if h.Parent != "" {
+		err = appendAlias(aliasesFile, oldParent, h.Parent)
+	} else {
+		err = appendAlias(aliasesFile, oldID, h.ID)
+	}
+	if err != nil {
+		return true, err
+	}
+	if err := saveScores(highlights); err != nil {
+		return true, err
+	}
+	if p, ok := loadCheckpoint(profilePath(*profile, checkpointFile)); ok && p.ID == oldID {
+		return true, removeCheckpoint(profilePath(*profile, checkpointFile))
+	}
+	return true, nil
+}
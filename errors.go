@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+)
+
+type DeckNotFoundError struct {
+	Path string
+}
+
+func (e *DeckNotFoundError) Error() string {
+	return fmt.Sprintf("deck %s not found, create it or run from the directory that contains it", e.Path)
+}
+
+type ScoreLineError struct {
+	Path   string
+	Line   int
+	Text   string
+	Reason string
+}
+
+func (e *ScoreLineError) Error() string {
+	return fmt.Sprintf("%s:%d: malformed score line %q: %s", e.Path, e.Line, e.Text, e.Reason)
+}
+
+type WriteError struct {
+	Path string
+	Err  error
+}
+
+func (e *WriteError) Error() string {
+	return fmt.Sprintf("could not write %s: %v", e.Path, e.Err)
+}
+
+func (e *WriteError) Unwrap() error {
+	return e.Err
+}
+
+type LockedError struct {
+	Path  string
+	Owner string
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("the data directory is in use by %s, remove %s if that session is no longer running", e.Owner, e.Path)
+}
+
+// InputError is a failed read of stdin, other than reaching its end.
+type InputError struct {
+	Err error
+}
+
+func (e *InputError) Error() string {
+	return fmt.Sprintf("could not read input: %v", e.Err)
+}
+
+func (e *InputError) Unwrap() error {
+	return e.Err
+}
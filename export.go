@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/arcana261/lifeinuk/sliceutils"
+)
+
+const (
+	exportJSON     = "json"
+	exportCSV      = "csv"
+	exportMarkdown = "markdown"
+)
+
+type exportToken struct {
+	Content string `json:"content"`
+	Start   int    `json:"start"`
+	End     int    `json:"end"`
+}
+
+type exportScore struct {
+	Count    int      `json:"count"`
+	Sum      float64  `json:"sum"`
+	Average  float64  `json:"average"`
+	Lapses   int      `json:"lapses"`
+	Flags    []string `json:"flags,omitempty"`
+	Reviewed string   `json:"reviewed,omitempty"`
+}
+
+type exportHighlight struct {
+	ID       string        `json:"id"`
+	Parent   string        `json:"parent,omitempty"`
+	Question string        `json:"question,omitempty"`
+	Source   string        `json:"source,omitempty"`
+	Content  string        `json:"content"`
+	Tokens   []exportToken `json:"tokens"`
+	Score    exportScore   `json:"score"`
+}
+
+// scoreBand is a section of the Markdown study sheet, holding the reviewed
+// highlights whose average is below Below.
+type scoreBand struct {
+	Title string
+	Below float64
+}
+
+var scoreBands = []scoreBand{
+	{Title: "Struggling", Below: leechRatio},
+	{Title: "Learning", Below: masteredAverage},
+	{Title: "Mastered", Below: 2},
+}
+
+// deckOrder lists the highlights the way they appear in the deck, sub-cards
+// in the order of their sentences, rather than in the order they are quizzed.
+func deckOrder(highlights HighlightDatabase) []*Highlight {
+	items := sliceutils.MapFunc(sliceutils.Range(0, len(highlights.Highlights)), func(i int) *Highlight {
+		return &highlights.Highlights[i]
+	})
+	return sliceutils.SortFunc(items, compareDeckOrder)
+}
+
+func compareDeckOrder(a, b *Highlight) int {
+	if a.Index != b.Index {
+		return a.Index - b.Index
+	}
+	return slices.Compare(subIndex(a.ID), subIndex(b.ID))
+}
+
+func exportHighlights(w io.Writer, highlights HighlightDatabase) error {
+	result := sliceutils.MapFunc(deckOrder(highlights), func(h *Highlight) exportHighlight {
+		content := []rune(h.Content)
+		var tokens []exportToken
+		for i := range h.Tokens {
+			tokens = append(tokens, exportToken{
+				Content: string(content[h.TokenStarts[i]:h.TokenEnds[i]]),
+				Start:   h.TokenStarts[i],
+				End:     h.TokenEnds[i],
+			})
+		}
+		return exportHighlight{
+			ID:       h.ID,
+			Parent:   h.Parent,
+			Question: h.Question,
+			Source:   h.Source,
+			Content:  h.Content,
+			Tokens:   tokens,
+			Score: exportScore{
+				Count:    h.Score.Count,
+				Sum:      h.Score.Sum,
+				Average:  h.Score.WeightedAverage(),
+				Lapses:   h.Score.Lapses,
+				Flags:    h.Score.Flags,
+				Reviewed: h.Score.Reviewed,
+			},
+		}
+	})
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(result)
+}
+
+func exportCSVRows(w io.Writer, highlights HighlightDatabase) error {
+	out := csv.NewWriter(w)
+	out.Write([]string{"ID", "Content", "Count", "Sum", "Average"})
+	for _, h := range deckOrder(highlights) {
+		out.Write([]string{
+			h.ID,
+			strings.Join(strings.Fields(h.Content), " "),
+			fmt.Sprintf("%d", h.Score.Count),
+			fmt.Sprintf("%f", h.Score.Sum),
+			fmt.Sprintf("%f", h.Score.WeightedAverage()),
+		})
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// exportStudySheet groups the highlights by average, weakest first, with the
+// highlights never reviewed at the end.
+func exportStudySheet(w io.Writer, highlights HighlightDatabase) error {
+	var buff bytes.Buffer
+	buff.WriteString("# Study sheet\n")
+
+	items := deckOrder(highlights)
+	reviewed := sliceutils.SortFunc(sliceutils.FilterFunc(items, func(h *Highlight) bool {
+		return h.Score.Count > 0
+	}), func(a, b *Highlight) int {
+		if c := CompareFloat64(a.Score.WeightedAverage(), b.Score.WeightedAverage()); c != 0 {
+			return c
+		}
+		return compareDeckOrder(a, b)
+	})
+
+	from := 0.0
+	for _, band := range scoreBands {
+		inBand := sliceutils.FilterFunc(reviewed, func(h *Highlight) bool {
+			return h.Score.WeightedAverage() >= from && h.Score.WeightedAverage() < band.Below
+		})
+		from = band.Below
+		if len(inBand) == 0 {
+			continue
+		}
+		fmt.Fprintf(&buff, "\n## %s (%d)\n\n", band.Title, len(inBand))
+		for _, h := range inBand {
+			fmt.Fprintf(&buff, "- %s *(average %.2f over %d reviews)*\n", studySheetText(h), h.Score.WeightedAverage(), h.Score.Count)
+		}
+	}
+
+	unseen := sliceutils.FilterFunc(items, func(h *Highlight) bool {
+		return h.Score.Count == 0
+	})
+	if len(unseen) > 0 {
+		fmt.Fprintf(&buff, "\n## Not reviewed (%d)\n\n", len(unseen))
+		for _, h := range unseen {
+			fmt.Fprintf(&buff, "- %s\n", studySheetText(h))
+		}
+	}
+
+	_, err := w.Write(buff.Bytes())
+	return err
+}
+
+func studySheetText(h *Highlight) string {
+	text := strings.Join(strings.Fields(h.Content), " ")
+	if h.Question != "" {
+		text = fmt.Sprintf("**%s** %s", strings.Join(strings.Fields(h.Question), " "), text)
+	}
+	return text
+}
+
+func cmdExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := fs.String("format", exportJSON, "output format: json, csv or markdown")
+	output := fs.String("o", "", "write to this file instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var export func(io.Writer, HighlightDatabase) error
+	switch *format {
+	case exportJSON:
+		export = exportHighlights
+	case exportCSV:
+		export = exportCSVRows
+	case exportMarkdown:
+		export = exportStudySheet
+	default:
+		return fmt.Errorf("unknown format %q, use %s, %s or %s", *format, exportJSON, exportCSV, exportMarkdown)
+	}
+
+	highlights, err := loadHighlights()
+	if err != nil {
+		return err
+	}
+
+	if *output == "" {
+		return export(stdout, highlights)
+	}
+	var buff bytes.Buffer
+	if err := export(&buff, highlights); err != nil {
+		return err
+	}
+	return writeFileAtomic(*output, buff.Bytes())
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var fuzzFragments = []string{
+	"a", "A", "d", "D", "b", "c", "C", "p", "P", "m", "M", "s", "o", "O", "t", "é",
+	"0", "1", "9", "10", "1066", "3.5", "50%", "12:30",
+	" ", "  ", ".", ",", ":", ";", "'", "\"", "-", "_", "*", "/", "\n", "\t", "‘", "’", "–",
+	"don", "'t", "you", "'ve", "'ll", "www", "http", "https", ".com", "://",
+	"AD", "BC", "pm", "am", "King's", "O'Neill",
+}
+
+func cmdTokenize(args []string) error {
+	fs := flag.NewFlagSet("tokenize", flag.ContinueOnError)
+	check := fs.String("check", "", "compare every *.txt in the directory with its .golden file")
+	update := fs.Bool("update", false, "rewrite .golden files instead of comparing them")
+	fuzz := fs.Int("fuzz", 0, "tokenize this many random inputs and verify token spans")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *fuzz > 0 {
+		return fuzzTokenizer(rand.New(rand.NewSource(*seed)), defaultTokenizer, *fuzz)
+	}
+	if *check != "" {
+		return checkGolden(defaultTokenizer, *check, *update)
+	}
+
+	if fs.NArg() == 0 {
+		b, err := io.ReadAll(stdin)
+		if err != nil {
+			return err
+		}
+		fmt.Fprint(stdout, formatGolden(defaultTokenizer.Tokenize(string(b))))
+		return nil
+	}
+	for _, fname := range fs.Args() {
+		b, err := os.ReadFile(fname)
+		if err != nil {
+			return err
+		}
+		fmt.Fprint(stdout, formatGolden(defaultTokenizer.Tokenize(string(b))))
+	}
+	return nil
+}
+
+func formatGolden(tokens []ParsedToken) string {
+	var buff bytes.Buffer
+	for _, token := range tokens {
+		buff.WriteString(fmt.Sprintf("%d\t%d\t%q\t%q\n", token.Start, token.End, token.Content, token.RealContent))
+	}
+	return buff.String()
+}
+
+func checkGolden(t *Tokenizer, dir string, update bool) error {
+	inputs, err := filepath.Glob(filepath.Join(dir, "*.txt"))
+	if err != nil {
+		return err
+	}
+	if len(inputs) == 0 {
+		return fmt.Errorf("no *.txt inputs found in %s", dir)
+	}
+
+	failed := 0
+	for _, input := range inputs {
+		b, err := os.ReadFile(input)
+		if err != nil {
+			return err
+		}
+		tokens := t.Tokenize(string(b))
+		if err := CheckSpans(string(b), tokens); err != nil {
+			return fmt.Errorf("%s: %w", input, err)
+		}
+		actual := formatGolden(tokens)
+
+		golden := strings.TrimSuffix(input, ".txt") + ".golden"
+		if update {
+			if err := os.WriteFile(golden, []byte(actual), 0644); err != nil {
+				return err
+			}
+			continue
+		}
+
+		expected, err := os.ReadFile(golden)
+		if err != nil {
+			return err
+		}
+		if string(expected) != actual {
+			failed = failed + 1
+			fmt.Fprintf(stdout, "%sFAIL%s %s\n", colorRed, colorNone, input)
+			printGoldenDiff(string(expected), actual)
+			continue
+		}
+		fmt.Fprintf(stdout, "%sok%s   %s\n", colorGreen, colorNone, input)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d golden files differ", failed, len(inputs))
+	}
+	return nil
+}
+
+func printGoldenDiff(expected string, actual string) {
+	expectedLines := strings.Split(expected, "\n")
+	actualLines := strings.Split(actual, "\n")
+	for i := 0; i < max(len(expectedLines), len(actualLines)); i++ {
+		var e, a string
+		if i < len(expectedLines) {
+			e = expectedLines[i]
+		}
+		if i < len(actualLines) {
+			a = actualLines[i]
+		}
+		if e != a {
+			fmt.Fprintf(stdout, "  line %d:\n    want: %s\n    got:  %s\n", i+1, e, a)
+		}
+	}
+}
+
+func fuzzTokenizer(r *rand.Rand, t *Tokenizer, count int) error {
+	for n := 0; n < count; n++ {
+		var buff bytes.Buffer
+		size := r.Intn(24)
+		for i := 0; i < size; i++ {
+			buff.WriteString(fuzzFragments[r.Intn(len(fuzzFragments))])
+		}
+		input := buff.String()
+		if err := CheckSpans(input, t.Tokenize(input)); err != nil {
+			return fmt.Errorf("input %q: %w", input, err)
+		}
+	}
+	fmt.Fprintf(stdout, "%d random inputs tokenized with valid spans\n", count)
+	return nil
+}
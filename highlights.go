@@ -7,9 +7,9 @@ import (
 	"fmt"
 	"math"
 	"math/rand"
-	"os"
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/arcana261/lifeinuk/maputils"
 	"github.com/arcana261/lifeinuk/sliceutils"
@@ -23,20 +23,48 @@ type HighlightDatabase struct {
 	Highlights      []Highlight
 	TokenMap        map[int]Token
 	UnmatchedScores map[string]Score
+	Phrases         phraseSet
+	Sources         map[string]*Highlight
+	Eligible        func(h Highlight) bool
+	Rand            *rand.Rand
+	// Deck is the deck text as last read or written, shared by all copies.
+	Deck *string
+}
+
+// DeckChanged reports whether the deck in s is no longer the one db was read
+// from, e.g. because another profile added to it since.
+func (db HighlightDatabase) DeckChanged(s Store) (bool, error) {
+	current, err := s.ReadDeck()
+	if err != nil {
+		return false, err
+	}
+	return db.Deck != nil && current != *db.Deck, nil
 }
 
 func (db HighlightDatabase) PickHighlight() *Highlight {
-	items := sliceutils.Range(0, len(db.Highlights))
-	minCount := sliceutils.MinFunc(db.Highlights, func(h1, h2 Highlight) int {
-		return h1.Score.Count - h2.Score.Count
-	}).Score.Count
-	items = sliceutils.FilterFunc(items, func(idx int) bool {
-		return db.Highlights[idx].Score.Count == minCount
+	today := time.Now().Format(dayFormat)
+	items := sliceutils.FilterFunc(sliceutils.Range(0, len(db.Highlights)), func(idx int) bool {
+		if !db.Highlights[idx].Score.Available(today) {
+			return false
+		}
+		return db.Eligible == nil || db.Eligible(db.Highlights[idx])
 	})
 	if len(items) == 0 {
 		return nil
 	}
-	target := rand.Float64() * db.Highlights[items[len(items)-1]].CumulativeProbability
+	starred := sliceutils.FilterFunc(items, func(idx int) bool {
+		return db.Highlights[idx].Score.HasFlag(flagStarred)
+	})
+	if len(starred) > 0 && db.Rand.Float64() < starredChance {
+		items = starred
+	}
+	minCount := db.Highlights[sliceutils.MinFunc(items, func(i1, i2 int) int {
+		return db.Highlights[i1].Score.Count - db.Highlights[i2].Score.Count
+	})].Score.Count
+	items = sliceutils.FilterFunc(items, func(idx int) bool {
+		return db.Highlights[idx].Score.Count == minCount
+	})
+	target := db.Rand.Float64() * db.Highlights[items[len(items)-1]].CumulativeProbability
 	at := sliceutils.LowerBoundSortedFunc(items, func(idx int) int {
 		return CompareFloat64(db.Highlights[idx].CumulativeProbability, target)
 	})
@@ -46,24 +74,119 @@ func (db HighlightDatabase) PickHighlight() *Highlight {
 	return &db.Highlights[items[at]]
 }
 
-func (db HighlightDatabase) WriteScore(fname string) error {
-	lines := sliceutils.MapFunc(
-		sliceutils.FilterFunc(db.Highlights, func(h Highlight) bool {
-			return h.Score.Count > 0
-		}), func(h Highlight) string {
-			return fmt.Sprintf("%s %f %d\n", h.ID, h.Score.Sum, h.Score.Count)
-		},
-	)
-	lines = append(lines,
-		sliceutils.MapFunc(
-			maputils.ToEntries(db.UnmatchedScores), func(p sliceutils.Pair[string, Score]) string {
-				return fmt.Sprintf("%s %f %d\n", p.Key, p.Value.Sum, p.Value.Count)
-			},
-		)...,
-	)
-	lines = sliceutils.Sort(lines)
+func (db HighlightDatabase) FindHighlight(id string) *Highlight {
+	index := sliceutils.IndexOfFunc(db.Highlights, func(h Highlight) bool {
+		return h.ID == id
+	})
+	if index < 0 {
+		return nil
+	}
+	return &db.Highlights[index]
+}
+
+// SetContent re-tokenizes a single highlight in place. New tokens are added
+// to TokenMap and next-token statistics are rebuilt only for the tokens that
+// appear in the old or the new content; the score stays with the highlight.
+func (db HighlightDatabase) SetContent(h *Highlight, content string, question string) {
+	parsed := mergePhrases(content, defaultTokenizer.Tokenize(content), db.Phrases)
+	affected := sliceutils.Clone(h.Tokens)
+
+	h.Content = content
+	h.Question = question
+	h.Tokens = db.internTokens(parsed)
+	h.TokenStarts = sliceutils.MapFunc(parsed, func(x ParsedToken) int {
+		return x.Start
+	})
+	h.TokenEnds = sliceutils.MapFunc(parsed, func(x ParsedToken) int {
+		return x.End
+	})
+	h.ID = generateID(sliceutils.MapFunc(parsed, func(x ParsedToken) string {
+		return x.Content
+	}))
+
+	db.rebuildNextTokens(sliceutils.UniqueSorted(sliceutils.Sort(append(affected, h.Tokens...))))
+}
+
+func (db HighlightDatabase) internTokens(parsed []ParsedToken) []int {
+	ids := make(map[string]int)
+	nextID := 0
+	for id, token := range db.TokenMap {
+		ids[token.Content] = id
+		nextID = max(nextID, id+1)
+	}
+
+	var result []int
+	for _, x := range parsed {
+		id, ok := ids[x.Content]
+		if !ok {
+			id = nextID
+			nextID = nextID + 1
+			ids[x.Content] = id
+			db.TokenMap[id] = Token{
+				ID:          id,
+				Content:     x.Content,
+				RealContent: x.RealContent,
+			}
+		}
+		result = append(result, id)
+	}
+	return result
+}
+
+func (db HighlightDatabase) rebuildNextTokens(affected []int) {
+	successors := make(map[int][]int)
+	for _, h := range db.Highlights {
+		for k := 0; k+1 < len(h.Tokens); k++ {
+			if sliceutils.Contains(affected, h.Tokens[k]) {
+				successors[h.Tokens[k]] = append(successors[h.Tokens[k]], h.Tokens[k+1])
+			}
+		}
+	}
 
-	return os.WriteFile(fname, []byte(strings.Join(lines, "")), 0644)
+	for _, t := range affected {
+		counts := make(map[int]int)
+		for _, next := range successors[t] {
+			counts[next] = counts[next] + 1
+		}
+
+		var nextTokens []NextToken
+		var cumulative float64
+		for _, id := range sliceutils.Sort(maputils.Keys(counts)) {
+			cumulative = cumulative + float64(counts[id])/float64(len(successors[t]))
+			nextTokens = append(nextTokens, NextToken{
+				ID:                    id,
+				CumulativeProbability: cumulative,
+			})
+		}
+
+		token := db.TokenMap[t]
+		token.NextTokens = nextTokens
+		db.TokenMap[t] = token
+	}
+
+	for id, token := range db.TokenMap {
+		token.AppearInNextToken = 0
+		db.TokenMap[id] = token
+	}
+	for _, token := range db.TokenMap {
+		for _, next := range token.NextTokens {
+			temp := db.TokenMap[next.ID]
+			temp.AppearInNextToken += 1
+			db.TokenMap[next.ID] = temp
+		}
+	}
+}
+
+func (db HighlightDatabase) Scores() map[string]Score {
+	result := maputils.MapFunc(db.UnmatchedScores, func(id string, s Score) (string, Score) {
+		return id, s
+	})
+	for _, h := range db.Highlights {
+		if !h.Score.IsEmpty() {
+			result[h.ID] = h.Score
+		}
+	}
+	return result
 }
 
 type Token struct {
@@ -82,12 +205,12 @@ func (t Token) NominateNextTokens(db HighlightDatabase, count int, skip ...strin
 		return db.TokenMap[nt.ID].SkipPuzzle || sliceutils.Contains(skip, db.TokenMap[nt.ID].Content)
 	})
 	for len(result) < count && len(nexts) > 0 {
-		target := rand.Float64() * nexts[len(nexts)-1].CumulativeProbability
+		target := db.Rand.Float64() * nexts[len(nexts)-1].CumulativeProbability
 		i := sliceutils.LowerBoundSortedFunc(nexts, func(nt NextToken) int {
 			return CompareFloat64(nt.CumulativeProbability, target)
 		})
 		if i < 0 {
-			fmt.Println(nexts)
+			fmt.Fprintln(stdout, nexts)
 		}
 		result = append(result, nexts[i].ID)
 		nexts = sliceutils.RemoveAt(nexts, i)
@@ -103,21 +226,80 @@ type NextToken struct {
 type Highlight struct {
 	ID                    string
 	Content               string
+	Question              string
+	Source                string
 	Tokens                []int
 	TokenStarts           []int
 	TokenEnds             []int
 	Score                 Score
 	CumulativeProbability float64
 	Index                 int
+	Parent                string
 }
 
 type Score struct {
-	Sum     float64
-	Count   int
-	Average float64
+	Sum      float64
+	Count    int
+	Average  float64
+	Lapses   int
+	Flags    []string
+	Reviewed string
+	Extra    []string
 }
 
-func WriteHighlights(db HighlightDatabase, fname string) {
+func (s Score) HasFlag(flag string) bool {
+	return sliceutils.Contains(s.Flags, flag)
+}
+
+func (s *Score) SetFlag(flag string) {
+	if !s.HasFlag(flag) {
+		s.Flags = sliceutils.Sort(append(sliceutils.Clone(s.Flags), flag))
+	}
+}
+
+func (s *Score) ClearFlag(flag string) {
+	s.Flags = sliceutils.Remove(s.Flags, flag)
+}
+
+func (s Score) WeightedAverage() float64 {
+	return s.Sum / (float64(s.Count)*float64(s.Count+1)/float64(2) + 1)
+}
+
+// Record scores a review of the highlight and queues it for the review
+// journal, which saveScores writes next to the scores.
+func (h *Highlight) Record(correctAnswers, wrongAnswers int) {
+	totalAnswers := max(1, correctAnswers+wrongAnswers)
+	h.RecordRatio(float64(correctAnswers) / float64(totalAnswers))
+}
+
+func (h *Highlight) RecordRatio(score float64) {
+	now := time.Now()
+	h.Score.RecordRatioAt(score, now)
+	pendingReviews = append(pendingReviews, Review{ID: h.ID, Time: now, Ratio: score})
+}
+
+func (s *Score) RecordRatioAt(score float64, at time.Time) {
+	if score < leechRatio {
+		s.Lapses = s.Lapses + 1
+		if s.Lapses >= leechLapses && !s.HasFlag(flagLeech) {
+			s.SetFlag(flagLeech)
+			if *leechAction == leechActionSuspend {
+				s.SetFlag(flagSuspended)
+			}
+		}
+	}
+
+	s.Count = s.Count + 1
+	score = score * float64(s.Count)
+	s.Sum = s.Sum + score
+	s.Reviewed = max(s.Reviewed, at.Local().Format(dayFormat))
+}
+
+func (s Score) IsEmpty() bool {
+	return s.Count == 0 && len(s.Flags) == 0 && len(s.Extra) == 0
+}
+
+func WriteHighlights(db HighlightDatabase, s Store) error {
 	var order []int
 	for i := 0; i < len(db.Highlights); i++ {
 		order = append(order, i)
@@ -136,30 +318,56 @@ func WriteHighlights(db HighlightDatabase, fname string) {
 	})
 
 	var buff bytes.Buffer
+	written := make(map[string]bool)
 	for i := 0; i < len(db.Highlights); i++ {
-		if i > 0 {
+		h := &db.Highlights[order[i]]
+		if h.Parent != "" {
+			if written[h.Parent] {
+				continue
+			}
+			written[h.Parent] = true
+			h = db.Sources[h.Parent]
+		}
+		if buff.Len() > 0 {
 			buff.WriteString("\n\n---\n\n")
 		}
-		buff.WriteString(db.Highlights[order[i]].Content)
+		buff.WriteString(entryText(h.Content, h.Question, h.Source))
 	}
-	err := os.WriteFile(fname, buff.Bytes(), 0644)
-	if err != nil {
-		panic(err)
+	deck := buff.String()
+	if db.Deck != nil && *db.Deck == deck {
+		return nil
+	}
+	if err := s.WriteDeck(deck); err != nil {
+		return err
+	}
+	if db.Deck != nil {
+		*db.Deck = deck
 	}
+	return nil
 }
 
-func ReadHighlights(fname string, scores string) (HighlightDatabase, error) {
-	bs, err := os.ReadFile(fname)
+func ReadHighlights(s Store, phrases string, aliases string) (HighlightDatabase, error) {
+	deck, err := s.ReadDeck()
 	if err != nil {
 		return HighlightDatabase{}, err
 	}
 
-	entries := sliceutils.Remove(
-		sliceutils.TrimSpace(
-			strings.Split(string(bs), "---"),
-		), "",
+	entries, questions, sources := splitQuestions(
+		sliceutils.Remove(
+			sliceutils.TrimSpace(
+				strings.Split(deck, entrySeparator),
+			), "",
+		),
 	)
-	entryTokens := sliceutils.MapFunc(entries, tokenizeString2)
+	entryTokens := sliceutils.MapFunc(entries, defaultTokenizer.Tokenize)
+	userPhrases, err := readPhrases(phrases)
+	if err != nil {
+		return HighlightDatabase{}, err
+	}
+	knownPhrases := newPhraseSet(append(userPhrases, detectPhrases(entries, entryTokens)...))
+	entryTokens = sliceutils.MapFunc2(entryTokens, func(index int, tokens []ParsedToken) ([]ParsedToken, bool) {
+		return mergePhrases(entries[index], tokens, knownPhrases), true
+	})
 	allTokens := sliceutils.ToMapFunc2(
 		sliceutils.UniqueSorted(
 			sliceutils.Sort(
@@ -198,10 +406,12 @@ func ReadHighlights(fname string, scores string) (HighlightDatabase, error) {
 		id := generateID(tokens)
 
 		return Highlight{
-			ID:      id,
-			Content: entry,
-			Index:   index,
-			Tokens:  sliceutils.Lookup(tokens, allTokens),
+			ID:       id,
+			Content:  entry,
+			Question: questions[index],
+			Source:   sources[index],
+			Index:    index,
+			Tokens:   sliceutils.Lookup(tokens, allTokens),
 			TokenStarts: sliceutils.MapFunc(item.Value, func(x ParsedToken) int {
 				return x.Start
 			}),
@@ -226,15 +436,19 @@ func ReadHighlights(fname string, scores string) (HighlightDatabase, error) {
 		), func(t int, nexts []int) (int, Token) {
 			nextTokens := sliceutils.AccumulateFunc2(
 				sliceutils.MapFunc(
-					maputils.ToEntries(
-						maputils.MapValuesFunc(
-							sliceutils.AccumulateFunc2(nexts, make(map[int]int), func(prev map[int]int, _ int, current int) (map[int]int, bool) {
-								prev[current] = prev[current] + 1
-								return prev, true
-							}), func(count int) float64 {
-								return float64(count) / float64(len(nexts))
-							},
-						),
+					sliceutils.SortFunc(
+						maputils.ToEntries(
+							maputils.MapValuesFunc(
+								sliceutils.AccumulateFunc2(nexts, make(map[int]int), func(prev map[int]int, _ int, current int) (map[int]int, bool) {
+									prev[current] = prev[current] + 1
+									return prev, true
+								}), func(count int) float64 {
+									return float64(count) / float64(len(nexts))
+								},
+							),
+						), func(a, b sliceutils.Pair[int, float64]) int {
+							return a.Key - b.Key
+						},
 					), func(p sliceutils.Pair[int, float64]) NextToken {
 						return NextToken{
 							ID:                    p.Key,
@@ -288,7 +502,7 @@ func ReadHighlights(fname string, scores string) (HighlightDatabase, error) {
 		if xv > yv {
 			return 1
 		}
-		return 0
+		return x - y
 	})
 	if len(tokenIDs) > 0 {
 		threshold := max(0, int(math.Floor(float64(len(tokenIDs))*UntrustedNext)))
@@ -302,13 +516,22 @@ func ReadHighlights(fname string, scores string) (HighlightDatabase, error) {
 	highlightIDToIndex := make(map[string]int)
 	for i := 0; i < len(result); i++ {
 		if _, ok := highlightIDToIndex[result[i].ID]; ok {
-			fmt.Printf("DUPLICAT ID FOUND!:\n========\n%s\n========\n", result[i].Content)
+			fmt.Fprintf(stdout, "DUPLICAT ID FOUND!:\n========\n%s\n========\n", result[i].Content)
 		}
 		highlightIDToIndex[result[i].ID] = i
 	}
 
+	knownScores, err := s.ReadScores()
+	if err != nil {
+		return HighlightDatabase{}, err
+	}
+	renamed, err := readAliases(aliases)
+	if err != nil {
+		return HighlightDatabase{}, err
+	}
+	knownScores = resolveScores(knownScores, renamed)
 	unmatchedScores := make(map[string]Score)
-	for id, score := range readScores(scores) {
+	for id, score := range knownScores {
 		index, ok := highlightIDToIndex[id]
 		if !ok {
 			unmatchedScores[id] = score
@@ -317,6 +540,18 @@ func ReadHighlights(fname string, scores string) (HighlightDatabase, error) {
 		result[index].Score = score
 	}
 
+	assignProbabilities(result)
+
+	return HighlightDatabase{
+		TokenMap:        resultTokenMap,
+		Highlights:      result,
+		UnmatchedScores: unmatchedScores,
+		Phrases:         knownPhrases,
+		Deck:            &deck,
+	}, nil
+}
+
+func assignProbabilities(result []Highlight) {
 	var sumScore float64
 	for i := 0; i < len(result); i++ {
 		f := 1.0 - result[i].Score.Average
@@ -338,12 +573,6 @@ func ReadHighlights(fname string, scores string) (HighlightDatabase, error) {
 	for i := 1; i < len(result); i++ {
 		result[i].CumulativeProbability = result[i].CumulativeProbability + result[i-1].CumulativeProbability
 	}
-
-	return HighlightDatabase{
-		TokenMap:        resultTokenMap,
-		Highlights:      result,
-		UnmatchedScores: unmatchedScores,
-	}, nil
 }
 
 func generateID(tokens []string) string {
@@ -358,267 +587,3 @@ func generateID(tokens []string) string {
 	sum := h.Sum(nil)
 	return base64.StdEncoding.EncodeToString(sum)
 }
-
-type ParsedToken struct {
-	Content     string
-	RealContent string
-	Start       int
-	End         int
-}
-
-func tokenizeString2(str string) []ParsedToken {
-	whitespace := map[rune]bool{
-		' ':  true,
-		'\t': true,
-		'\r': true,
-		'\n': true,
-	}
-	seperators := map[rune]bool{
-		'.':      true,
-		'"':      true,
-		',':      true,
-		':':      true,
-		'\n':     true,
-		'\r':     true,
-		'\t':     true,
-		' ':      true,
-		';':      true,
-		'\'':     true,
-		'-':      true,
-		'_':      true,
-		'=':      true,
-		'+':      true,
-		'#':      true,
-		'(':      true,
-		')':      true,
-		'[':      true,
-		']':      true,
-		'{':      true,
-		'}':      true,
-		'!':      true,
-		'\u2018': true,
-		'\u2019': true,
-		'\u2013': true,
-	}
-
-	accompanys := map[rune]bool{
-		'\'':     true,
-		'"':      true,
-		'\u2018': true,
-		'\u2019': true,
-		'\u2013': true,
-	}
-
-	digits := map[rune]bool{
-		'0': true,
-		'1': true,
-		'2': true,
-		'3': true,
-		'4': true,
-		'5': true,
-		'6': true,
-		'7': true,
-		'8': true,
-		'9': true,
-	}
-
-	input := []rune(str)
-
-	var tokens []ParsedToken
-	var current []rune
-	var tokenStart int
-	isNumber := true
-	isURL := false
-
-	for i := 0; i < len(input); i++ {
-		r := input[i]
-
-		if len(current) == 0 && seperators[r] {
-			continue
-		}
-		if accompanys[r] && i+1 < len(input) && ((i > 0 && (input[i-1] == 'o' || input[i-1] == 'O')) || input[i+1] == 's') {
-			current = append(current, '\'')
-			current = append(current, input[i+1])
-			i = i + 1
-			continue
-		}
-		if (r == ',' || r == '.' || r == ':') && len(current) > 0 && digits[current[len(current)-1]] && i+1 < len(input) && digits[input[i+1]] {
-			continue
-		}
-		if r == '*' {
-			continue
-		}
-
-		if seperators[r] {
-
-			if isNumber {
-				j := i
-				for j < len(input) && whitespace[input[j]] {
-					j = j + 1
-				}
-
-				if j+1 < len(input) {
-					isP := false
-					isA := false
-					isM := false
-					isB := false
-					isC := false
-					isD := false
-					switch input[j] {
-					case 'p':
-						isP = true
-					case 'P':
-						isP = true
-					case 'a':
-						isA = true
-					case 'A':
-						isA = true
-					case 'b':
-						isB = true
-					case 'B':
-						isB = true
-					default:
-					}
-					switch input[j+1] {
-					case 'm':
-						isM = true
-					case 'M':
-						isM = true
-					case 'd':
-						isD = true
-					case 'D':
-						isD = true
-					case 'c':
-						isC = true
-					case 'C':
-						isC = true
-					default:
-					}
-
-					if (isA && isM) || (isB && isC) || (isP && isM) || (isA && isD) {
-						i = j + 2
-						current = append(current, input[j])
-						current = append(current, input[j+1])
-					}
-				}
-			}
-
-			if isNumber && len(tokens) > 0 && (tokens[len(tokens)-1].Content == "ad" || tokens[len(tokens)-1].Content == "bc") {
-				last := tokens[len(tokens)-1]
-				tokens[len(tokens)-1] = ParsedToken{
-					Content:     fmt.Sprintf("%s %s", last.Content, strings.ToLower(string(current))),
-					Start:       last.Start,
-					End:         i,
-					RealContent: string(input[last.Start:i]),
-				}
-				isURL = false
-			} else if tokenStart > 0 && (input[tokenStart-1] == '.' || input[tokenStart-1] == '/' || input[tokenStart-1] == '-' || input[tokenStart-1] == ':') && isURL {
-				last := tokens[len(tokens)-1]
-				tokens[len(tokens)-1] = ParsedToken{
-					Content:     fmt.Sprintf("%s.%s", last.Content, strings.ToLower(string(current))),
-					Start:       last.Start,
-					End:         i,
-					RealContent: string(input[last.Start:i]),
-				}
-			} else if len(current) == 1 && (current[0] == 't' || current[0] == 'T') && tokenStart > 0 && input[tokenStart-1] == '\'' && len(tokens) > 0 && tokens[len(tokens)-1].Content == "don" {
-				last := tokens[len(tokens)-1]
-				tokens[len(tokens)-1] = ParsedToken{
-					Content:     fmt.Sprintf("%s'%s", last.Content, strings.ToLower(string(current))),
-					Start:       last.Start,
-					End:         i,
-					RealContent: string(input[last.Start:i]),
-				}
-				isURL = false
-			} else if (strings.ToLower(string(current)) == "ve" || strings.ToLower(string(current)) == "ll") && tokenStart > 0 && input[tokenStart-1] == '\'' && len(tokens) > 0 && tokens[len(tokens)-1].Content == "you" {
-				last := tokens[len(tokens)-1]
-				tokens[len(tokens)-1] = ParsedToken{
-					Content:     fmt.Sprintf("%s'%s", last.Content, strings.ToLower(string(current))),
-					Start:       last.Start,
-					End:         i,
-					RealContent: string(input[last.Start:i]),
-				}
-				isURL = false
-			} else {
-				tokens = append(tokens, ParsedToken{
-					Content:     strings.ToLower(string(current)),
-					Start:       tokenStart,
-					End:         i,
-					RealContent: string(input[tokenStart:i]),
-				})
-				isURL = tokens[len(tokens)-1].RealContent == "www" || tokens[len(tokens)-1].RealContent == "http" || tokens[len(tokens)-1].RealContent == "https"
-			}
-
-			current = nil
-			isNumber = true
-			continue
-		}
-
-		if len(current) == 0 {
-			tokenStart = i
-		}
-		if !digits[r] && r != '.' && r != '-' && r != '+' && r != '%' && r != ',' && r != ':' {
-			isNumber = false
-		}
-		current = append(current, r)
-	}
-
-	if len(current) > 0 {
-		tokens = append(tokens, ParsedToken{
-			Content:     strings.ToLower(string(current)),
-			Start:       tokenStart,
-			End:         len(input),
-			RealContent: string(input[tokenStart:]),
-		})
-	}
-
-	/*for _, item := range tokens {
-		fmt.Printf("%s | %s\n", item.Content, item.RealContent)
-	}*/
-
-	return tokens
-}
-
-func readScores(fname string) map[string]Score {
-	if !fileExists(fname) {
-		return nil
-	}
-
-	b, err := os.ReadFile(fname)
-	if err != nil {
-		panic(err)
-	}
-
-	lines := strings.Split(string(b), "\n")
-	lines = sliceutils.TrimSpace(lines)
-	parts := sliceutils.Split(lines, " ")
-	parts = sliceutils.FilterFunc(parts, func(s []string) bool { return len(s) == 3 })
-	pairs := sliceutils.MapFunc(parts, func(part []string) sliceutils.Pair[string, Score] {
-		id := strings.TrimSpace(part[0])
-		var totalSum float64
-		var count int
-		n, err := fmt.Sscanf(strings.TrimSpace(part[1]), "%f", &totalSum)
-		if n != 1 || err != nil {
-			return sliceutils.Pair[string, Score]{}
-		}
-		n, err = fmt.Sscanf(strings.TrimSpace(part[2]), "%d", &count)
-		if n != 1 || err != nil {
-			return sliceutils.Pair[string, Score]{}
-		}
-
-		return sliceutils.Pair[string, Score]{
-			Key: id,
-			Value: Score{
-				Sum:     totalSum,
-				Count:   count,
-				Average: totalSum / (float64(count)*float64(count+1)/float64(2) + 1),
-			},
-		}
-	})
-	pairs = sliceutils.FilterFunc(pairs, func(p sliceutils.Pair[string, Score]) bool {
-		return p.Key != ""
-	})
-	result := sliceutils.ToMapFunc(pairs, func(p sliceutils.Pair[string, Score]) (string, Score) {
-		return p.Key, p.Value
-	})
-	return result
-}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"unicode"
+
+	"github.com/arcana261/lifeinuk/sliceutils"
+)
+
+const (
+	maxHints    = 3
+	hintPenalty = 0.25
+)
+
+func tokenHint(realContent string, level int) string {
+	rs := []rune(realContent)
+	if len(rs) == 0 {
+		return ""
+	}
+	if level < 2 {
+		return fmt.Sprintf("%s...", string(rs[:1]))
+	}
+
+	letters := 0
+	masked := make([]rune, len(rs))
+	for k, r := range rs {
+		masked[k] = r
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			letters = letters + 1
+			if k > 0 {
+				masked[k] = '_'
+			}
+		}
+	}
+	return fmt.Sprintf("%s (%d letters)", string(masked), letters)
+}
+
+func hintCredit(hints int) float64 {
+	return max(0, 1-hintPenalty*float64(hints))
+}
+
+func eliminateDistractor(r *rand.Rand, choices []int, answer int) []int {
+	if len(choices) <= 2 {
+		return choices
+	}
+	distractors := sliceutils.FilterFunc(sliceutils.Range(0, len(choices)), func(j int) bool {
+		return choices[j] != answer
+	})
+	return sliceutils.RemoveAt(choices, distractors[r.Intn(len(distractors))])
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	importAuto     = "auto"
+	importKindle   = "kindle"
+	importMarkdown = "markdown"
+
+	kindleSeparator = "=========="
+)
+
+// importedEntry is a highlight read from an e-book export together with where
+// it came from, e.g. "Life in the UK (Home Office), location 120-122".
+type importedEntry struct {
+	Content string
+	Source  string
+}
+
+var (
+	markdownHeading = regexp.MustCompile(`^(#+)\s+(.*)$`)
+	markdownItem    = regexp.MustCompile(`^(?:[-*+]|\d+[.)])\s+(.*)$`)
+)
+
+// parseKindleClippings reads Kindle's "My Clippings.txt": entries separated
+// by a line of equal signs, each a title line, a "- Your Highlight ..." line,
+// an empty line and the text. Notes and bookmarks are skipped.
+func parseKindleClippings(text string) []importedEntry {
+	var result []importedEntry
+	text = strings.TrimPrefix(strings.ReplaceAll(text, "\r\n", "\n"), "\ufeff")
+	for _, clipping := range strings.Split(text, kindleSeparator) {
+		lines := strings.Split(strings.TrimSpace(clipping), "\n")
+		if len(lines) < 3 {
+			continue
+		}
+		title := strings.TrimSpace(strings.TrimPrefix(lines[0], "\ufeff"))
+		meta := strings.TrimSpace(lines[1])
+		if !strings.HasPrefix(meta, "- Your Highlight") {
+			continue
+		}
+		content := strings.TrimSpace(strings.Join(lines[2:], "\n"))
+		if content == "" {
+			continue
+		}
+
+		source := []string{title}
+		for _, part := range strings.Split(strings.TrimPrefix(meta, "- Your Highlight"), "|") {
+			part = strings.TrimSpace(part)
+			if part == "" || strings.HasPrefix(part, "Added on") {
+				continue
+			}
+			source = append(source, strings.ToLower(strings.TrimPrefix(part, "on ")))
+		}
+		result = append(result, importedEntry{Content: content, Source: strings.Join(source, ", ")})
+	}
+	return result
+}
+
+// parseAnnotations reads a Markdown or plain text export. Headings name the
+// book and chapter the following highlights come from. If there are quotes,
+// every quoted paragraph is a highlight; otherwise list items are, and
+// without those every paragraph is.
+func parseAnnotations(text string, title string) []importedEntry {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	quoted := false
+	listed := false
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		quoted = quoted || strings.HasPrefix(line, ">")
+		listed = listed || markdownItem.MatchString(line)
+	}
+
+	var result []importedEntry
+	headings := []string{title}
+	var paragraph []string
+	flush := func() {
+		content := strings.TrimSpace(strings.Join(paragraph, "\n"))
+		paragraph = nil
+		if content != "" {
+			result = append(result, importedEntry{Content: content, Source: strings.Join(headings, ", ")})
+		}
+	}
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if m := markdownHeading.FindStringSubmatch(line); m != nil {
+			flush()
+			level := len(m[1])
+			if level == 1 {
+				headings = []string{m[2]}
+			} else {
+				headings = append(headings[:min(level-1, len(headings))], m[2])
+			}
+			continue
+		}
+		switch {
+		case quoted:
+			if !strings.HasPrefix(line, ">") {
+				flush()
+				continue
+			}
+			line = strings.TrimSpace(strings.TrimPrefix(line, ">"))
+			if line == "" {
+				flush()
+				continue
+			}
+		case listed:
+			m := markdownItem.FindStringSubmatch(line)
+			if m != nil {
+				flush()
+				line = m[1]
+			} else if line == "" || len(paragraph) == 0 {
+				flush()
+				continue
+			}
+		case line == "":
+			flush()
+			continue
+		}
+		paragraph = append(paragraph, line)
+	}
+	flush()
+	return result
+}
+
+func importFormat(fname string, text string) string {
+	if filepath.Base(fname) == "My Clippings.txt" || strings.Contains(text, "\n"+kindleSeparator) {
+		return importKindle
+	}
+	return importMarkdown
+}
+
+func cmdImport(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	format := fs.String("format", importAuto, "format of the files: kindle (My Clippings.txt), markdown (Markdown or plain text annotations) or auto")
+	force := fs.Bool("force", false, "import near duplicates too")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("usage: import [-format kindle|markdown] [-force] FILE...")
+	}
+	if *format != importAuto && *format != importKindle && *format != importMarkdown {
+		return fmt.Errorf("unknown format %q, use %s, %s or %s", *format, importKindle, importMarkdown, importAuto)
+	}
+
+	var imported []importedEntry
+	for _, fname := range fs.Args() {
+		bs, err := os.ReadFile(fname)
+		if err != nil {
+			return err
+		}
+		text := string(bs)
+		kind := *format
+		if kind == importAuto {
+			kind = importFormat(fname, text)
+		}
+		if kind == importKindle {
+			imported = append(imported, parseKindleClippings(text)...)
+		} else {
+			imported = append(imported, parseAnnotations(text, strings.TrimSuffix(filepath.Base(fname), filepath.Ext(fname)))...)
+		}
+	}
+	if len(imported) == 0 {
+		return fmt.Errorf("no highlights found")
+	}
+
+	unlock, err := lockData()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	highlights, err := loadHighlights()
+	if err != nil {
+		return err
+	}
+
+	var entries, questions, sources []string
+	for _, e := range imported {
+		entries = append(entries, e.Content)
+		questions = append(questions, "")
+		sources = append(sources, strings.Join(strings.Fields(e.Source), " "))
+	}
+	added, err := pickNewEntries(highlights, entries, questions, sources, *force, false)
+	if err != nil {
+		return err
+	}
+	if len(added) == 0 {
+		fmt.Fprintf(stdout, "\nNothing imported\n")
+		return nil
+	}
+	unlockDeck, err := lockDeck()
+	if err != nil {
+		return err
+	}
+	defer unlockDeck()
+	if err := appendHighlights(store, added); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "\nImported %d of %d highlights\n", len(added), len(entries))
+	return nil
+}
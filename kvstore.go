@@ -0,0 +1,432 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/arcana261/lifeinuk/maputils"
+	"github.com/arcana261/lifeinuk/sliceutils"
+)
+
+// The db store is a single append-only file: a magic line followed by
+// records of
+//
+//	op (1 byte) | key length (uvarint) | key | value length (uvarint) | value | crc32 (4 bytes)
+//
+// Puts and deletes only take effect once a commit record follows them, so a
+// write cut short by a crash is skipped by readers and cut off by the next
+// write. The file is
+// rewritten with only the live keys when it grows well past them. Scores of a
+// named profile are kept under their own key prefix next to the shared deck,
+// and an "imported/" key marks each prefix whose text scores were taken over.
+const (
+	dbMagic        = "lifeinuk db v1\n"
+	dbKeyDeck      = "deck"
+	dbKeyScore     = "score/"
+	dbKeyProfile   = "profile/"
+	dbKeyImported  = "imported/"
+	dbCompactSlack = 1000
+	dbLockSuffix   = ".lock"
+
+	dbOpPut    = 1
+	dbOpDelete = 2
+	dbOpCommit = 3
+)
+
+type dbRecord struct {
+	Op    byte
+	Key   string
+	Value string
+}
+
+type dbStore struct {
+	Path   string
+	Prefix string
+	// Seed is read until the db file exists and filled into it by the
+	// first write.
+	Seed    Store
+	file    *os.File
+	info    os.FileInfo
+	values  map[string]string
+	records int
+	// committed is the length of the file up to its last commit record,
+	// size the length of the whole file when it was read.
+	committed int64
+	size      int64
+}
+
+// openDBStore only reads the file. Anything that changes it, creating it,
+// cutting off an unfinished commit or compacting it, is left to the writes,
+// which take the db lock since every profile shares the file.
+func openDBStore(fname string, seed Store, name string) (*dbStore, error) {
+	s := &dbStore{Path: fname, Prefix: dbKeyScore, Seed: seed, values: make(map[string]string)}
+	if name != "" {
+		s.Prefix = dbKeyProfile + name + "/" + dbKeyScore
+	}
+	if err := s.refresh(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// refresh reads the file again if another session committed to or compacted
+// it since it was last read.
+func (s *dbStore) refresh() error {
+	info, err := os.Stat(s.Path)
+	if os.IsNotExist(err) {
+		s.values = make(map[string]string)
+		s.info = nil
+		s.records, s.committed, s.size = 0, 0, 0
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if s.info != nil && os.SameFile(info, s.info) && info.Size() == s.size {
+		return nil
+	}
+	return s.load()
+}
+
+// load reads every committed record. An unfinished commit at the end is
+// skipped; it may still be being written by another session.
+func (s *dbStore) load() error {
+	f, err := os.Open(s.Path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	bs, err := io.ReadAll(f)
+	if err != nil {
+		return err
+	}
+	if !bytes.HasPrefix(bs, []byte(dbMagic)) {
+		return fmt.Errorf("%s is not a lifeinuk database", s.Path)
+	}
+
+	s.values = make(map[string]string)
+	s.records = 0
+	r := bytes.NewReader(bs[len(dbMagic):])
+	committed := int64(len(dbMagic))
+	var pending []dbRecord
+	for r.Len() > 0 {
+		record, err := readDBRecord(r)
+		if err != nil {
+			break
+		}
+		s.records = s.records + 1
+		if record.Op != dbOpCommit {
+			pending = append(pending, record)
+			continue
+		}
+		s.apply(pending)
+		pending = nil
+		committed = int64(len(bs) - r.Len())
+	}
+	s.info = info
+	s.committed = committed
+	s.size = int64(len(bs))
+	return nil
+}
+
+// prepareWrite makes the file ready for appending: it is created and filled
+// from the seed if it does not exist yet, and an unfinished commit left by a
+// crashed session is cut off. Callers hold the db lock, so no other session
+// is writing.
+func (s *dbStore) prepareWrite() error {
+	if err := s.refresh(); err != nil {
+		return err
+	}
+	if s.info == nil {
+		if err := writeFileAtomic(s.Path, []byte(dbMagic)); err != nil {
+			return err
+		}
+		if err := s.load(); err != nil {
+			return err
+		}
+		if err := s.openFile(); err != nil {
+			return err
+		}
+		if s.Seed != nil {
+			return s.importDeck(s.Seed)
+		}
+		return nil
+	}
+	if s.committed < s.size {
+		if err := os.Truncate(s.Path, s.committed); err != nil {
+			return &WriteError{Path: s.Path, Err: err}
+		}
+		if err := s.load(); err != nil {
+			return err
+		}
+	}
+	return s.openFile()
+}
+
+// openFile opens the append handle, again if the file was replaced by a
+// compaction since.
+func (s *dbStore) openFile() error {
+	if s.file != nil {
+		info, err := s.file.Stat()
+		if err == nil && os.SameFile(info, s.info) {
+			return nil
+		}
+		s.file.Close()
+		s.file = nil
+	}
+	f, err := os.OpenFile(s.Path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return &WriteError{Path: s.Path, Err: err}
+	}
+	s.file = f
+	return nil
+}
+
+func readDBRecord(r *bytes.Reader) (dbRecord, error) {
+	start := r.Size() - int64(r.Len())
+	op, err := r.ReadByte()
+	if err != nil {
+		return dbRecord{}, err
+	}
+	key, err := readDBString(r)
+	if err != nil {
+		return dbRecord{}, err
+	}
+	value, err := readDBString(r)
+	if err != nil {
+		return dbRecord{}, err
+	}
+	end := r.Size() - int64(r.Len())
+
+	var sum uint32
+	if err := binary.Read(r, binary.LittleEndian, &sum); err != nil {
+		return dbRecord{}, err
+	}
+	body := make([]byte, end-start)
+	if _, err := r.ReadAt(body, start); err != nil {
+		return dbRecord{}, err
+	}
+	if crc32.ChecksumIEEE(body) != sum {
+		return dbRecord{}, errors.New("checksum mismatch")
+	}
+	return dbRecord{Op: op, Key: key, Value: value}, nil
+}
+
+func readDBString(r *bytes.Reader) (string, error) {
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		return "", err
+	}
+	if size > uint64(r.Len()) {
+		return "", io.ErrUnexpectedEOF
+	}
+	buff := make([]byte, size)
+	if _, err := io.ReadFull(r, buff); err != nil {
+		return "", err
+	}
+	return string(buff), nil
+}
+
+func appendDBRecord(buff *bytes.Buffer, record dbRecord) {
+	var body bytes.Buffer
+	body.WriteByte(record.Op)
+	body.Write(binary.AppendUvarint(nil, uint64(len(record.Key))))
+	body.WriteString(record.Key)
+	body.Write(binary.AppendUvarint(nil, uint64(len(record.Value))))
+	body.WriteString(record.Value)
+	buff.Write(body.Bytes())
+	binary.Write(buff, binary.LittleEndian, crc32.ChecksumIEEE(body.Bytes()))
+}
+
+func (s *dbStore) apply(records []dbRecord) {
+	for _, record := range records {
+		switch record.Op {
+		case dbOpPut:
+			s.values[record.Key] = record.Value
+		case dbOpDelete:
+			delete(s.values, record.Key)
+		}
+	}
+}
+
+// commit appends the records and a commit marker in one write and syncs the
+// file before the in-memory view is updated. The file is compacted once it
+// has grown well past its live keys.
+func (s *dbStore) commit(records []dbRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+	var buff bytes.Buffer
+	for _, record := range records {
+		appendDBRecord(&buff, record)
+	}
+	appendDBRecord(&buff, dbRecord{Op: dbOpCommit})
+
+	if _, err := s.file.Write(buff.Bytes()); err != nil {
+		return &WriteError{Path: s.Path, Err: err}
+	}
+	if err := s.file.Sync(); err != nil {
+		return &WriteError{Path: s.Path, Err: err}
+	}
+	s.apply(records)
+	s.records = s.records + len(records) + 1
+	s.committed = s.committed + int64(buff.Len())
+	s.size = s.committed
+
+	if s.records > 2*len(s.values)+dbCompactSlack {
+		return s.compact()
+	}
+	return nil
+}
+
+func (s *dbStore) compact() error {
+	var buff bytes.Buffer
+	buff.WriteString(dbMagic)
+	keys := sliceutils.Sort(maputils.Keys(s.values))
+	for _, key := range keys {
+		appendDBRecord(&buff, dbRecord{Op: dbOpPut, Key: key, Value: s.values[key]})
+	}
+	appendDBRecord(&buff, dbRecord{Op: dbOpCommit})
+
+	if err := writeFileAtomic(s.Path, buff.Bytes()); err != nil {
+		return err
+	}
+	if err := s.load(); err != nil {
+		return err
+	}
+	return s.openFile()
+}
+
+func (s *dbStore) importDeck(seed Store) error {
+	deck, err := seed.ReadDeck()
+	var notFound *DeckNotFoundError
+	if errors.As(err, &notFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return s.commit([]dbRecord{{Op: dbOpPut, Key: dbKeyDeck, Value: deck}})
+}
+
+// scoresImported reports whether the scores of this profile live in the db.
+// Until its first write, every profile, not only the one that created the
+// file, keeps reading its text scores file.
+func (s *dbStore) scoresImported() bool {
+	if _, ok := s.values[dbKeyImported+s.Prefix]; ok {
+		return true
+	}
+	for key := range s.values {
+		if strings.HasPrefix(key, s.Prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *dbStore) ReadDeck() (string, error) {
+	if err := s.refresh(); err != nil {
+		return "", err
+	}
+	if s.info == nil && s.Seed != nil {
+		return s.Seed.ReadDeck()
+	}
+	deck, ok := s.values[dbKeyDeck]
+	if !ok {
+		return "", &DeckNotFoundError{Path: s.Path}
+	}
+	return deck, nil
+}
+
+// lock takes the db lock for one write.
+func (s *dbStore) lock() (func(), error) {
+	return waitLock(s.Path + dbLockSuffix)
+}
+
+func (s *dbStore) WriteDeck(deck string) error {
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if err := s.prepareWrite(); err != nil {
+		return err
+	}
+	if current, ok := s.values[dbKeyDeck]; ok && current == deck {
+		return nil
+	}
+	return s.commit([]dbRecord{{Op: dbOpPut, Key: dbKeyDeck, Value: deck}})
+}
+
+func (s *dbStore) ReadScores() (map[string]Score, error) {
+	if err := s.refresh(); err != nil {
+		return nil, err
+	}
+	if !s.scoresImported() && s.Seed != nil {
+		return s.Seed.ReadScores()
+	}
+	today := time.Now().Format(dayFormat)
+	result := make(map[string]Score)
+	var errs []error
+	for key, value := range s.values {
+		if !strings.HasPrefix(key, s.Prefix) {
+			continue
+		}
+		id, score, reason := parseScore(strings.Fields(value))
+		if reason != "" {
+			errs = append(errs, fmt.Errorf("%s: malformed score %q: %s", s.Path, value, reason))
+			continue
+		}
+		result[id] = normalizeScore(score, today)
+	}
+	return result, errors.Join(errs...)
+}
+
+func (s *dbStore) WriteScores(scores map[string]Score) error {
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if err := s.prepareWrite(); err != nil {
+		return err
+	}
+	var records []dbRecord
+	if _, ok := s.values[dbKeyImported+s.Prefix]; !ok {
+		records = append(records, dbRecord{Op: dbOpPut, Key: dbKeyImported + s.Prefix})
+	}
+	for _, id := range sliceutils.Sort(maputils.Keys(scores)) {
+		value := strings.TrimSpace(formatScore(id, scores[id]))
+		if current, ok := s.values[s.Prefix+id]; !ok || current != value {
+			records = append(records, dbRecord{Op: dbOpPut, Key: s.Prefix + id, Value: value})
+		}
+	}
+	for key := range s.values {
+		if id, ok := strings.CutPrefix(key, s.Prefix); ok {
+			if _, ok := scores[id]; !ok {
+				records = append(records, dbRecord{Op: dbOpDelete, Key: key})
+			}
+		}
+	}
+	return s.commit(records)
+}
+
+func (s *dbStore) Close() error {
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
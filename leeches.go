@@ -0,0 +1,90 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/arcana261/lifeinuk/sliceutils"
+)
+
+const (
+	flagLeech = "leech"
+
+	leechRatio  = 0.5
+	leechLapses = 4
+
+	leechActionShow    = "show"
+	leechActionSuspend = "suspend"
+)
+
+var leechAction = flag.String("leech", leechActionShow, "what to do with leeches: show (read in full before the game) or suspend")
+
+func showLeech(h *Highlight) error {
+	if !h.Score.HasFlag(flagLeech) || *leechAction != leechActionShow {
+		return nil
+	}
+
+	fmt.Fprintf(stdout, "\n%sThis card is a leech, read it once more before playing:%s\n", colorRed, colorNone)
+	printContent(h.Content)
+	fmt.Fprintf(stdout, "Press any key to continue\n\n")
+	_, err := readOne()
+	return err
+}
+
+func leeches(highlights HighlightDatabase) []*Highlight {
+	var result []*Highlight
+	for i := range highlights.Highlights {
+		if highlights.Highlights[i].Score.HasFlag(flagLeech) {
+			result = append(result, &highlights.Highlights[i])
+		}
+	}
+	return sliceutils.SortFunc(result, func(a, b *Highlight) int {
+		return b.Score.Lapses - a.Score.Lapses
+	})
+}
+
+func cmdLeeches(args []string) error {
+	fs := flag.NewFlagSet("leeches", flag.ContinueOnError)
+	reset := fs.String("reset", "", "clear the leech status of the highlight with this ID, e.g. after editing it")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	// Resetting saves scores, so they must not change between the load and
+	// the save.
+	if *reset != "" {
+		unlock, err := lockData()
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
+
+	highlights, err := loadHighlights()
+	if err != nil {
+		return err
+	}
+
+	if *reset != "" {
+		h := highlights.FindHighlight(*reset)
+		if h == nil {
+			return fmt.Errorf("no highlight with ID %s", *reset)
+		}
+		h.Score.Lapses = 0
+		h.Score.ClearFlag(flagLeech)
+		h.Score.ClearFlag(flagSuspended)
+		return saveScores(highlights)
+	}
+
+	for _, h := range leeches(highlights) {
+		status := ""
+		if h.Score.HasFlag(flagSuspended) {
+			status = fmt.Sprintf(" %s(suspended)%s", colorYellow, colorNone)
+		}
+		fmt.Fprintf(stdout, "%s#%d%s %s%s\n", colorBlue, h.Index+1, colorNone, h.ID, status)
+		fmt.Fprintf(stdout, "  %d lapses in %d reviews, average %.2f\n", h.Score.Lapses, h.Score.Count, h.Score.WeightedAverage())
+		fmt.Fprintf(stdout, "  %s\n\n", strings.Join(strings.Fields(h.Content), " "))
+	}
+	return nil
+}
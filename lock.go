@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	lockFile = ".lock"
+
+	lockWait  = 3 * time.Second
+	lockRetry = 50 * time.Millisecond
+)
+
+func deckLockPath() string {
+	return filepath.Join(filepath.Dir(highlightsFile), lockFile)
+}
+
+// lockData makes sure only one session at a time works with the scores of
+// the profile, the default one included, so several profiles can study the
+// same deck at once. Everything that writes to the profile takes it first,
+// so this is where a new profile's directory is created. The returned
+// function releases the lock.
+func lockData() (func(), error) {
+	if *profile != "" {
+		if _, err := os.Stat(filepath.Dir(profilesDir)); os.IsNotExist(err) {
+			return nil, &DeckNotFoundError{Path: highlightsFile}
+		}
+		if err := os.MkdirAll(profilePath(*profile, ""), 0755); err != nil {
+			return nil, &WriteError{Path: profilePath(*profile, ""), Err: err}
+		}
+	}
+	return takeLock(profilePath(*profile, lockFile))
+}
+
+// lockDeck guards a read and write of the shared deck, waiting a little for
+// another profile's write to finish.
+func lockDeck() (func(), error) {
+	return waitLock(deckLockPath())
+}
+
+// waitLock takes the lock in fname, retrying for a while if another session
+// holds it.
+func waitLock(fname string) (func(), error) {
+	deadline := time.Now().Add(lockWait)
+	for {
+		unlock, err := takeLock(fname)
+		var locked *LockedError
+		if !errors.As(err, &locked) || time.Now().After(deadline) {
+			return unlock, err
+		}
+		time.Sleep(lockRetry)
+	}
+}
+
+func takeLock(fname string) (func(), error) {
+	f, err := os.OpenFile(fname, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, &DeckNotFoundError{Path: highlightsFile}
+	}
+	if errors.Is(err, os.ErrExist) {
+		owner := "another session"
+		if b, err := os.ReadFile(fname); err == nil && strings.TrimSpace(string(b)) != "" {
+			owner = fmt.Sprintf("process %s", strings.TrimSpace(string(b)))
+		}
+		return nil, &LockedError{Path: fname, Owner: owner}
+	}
+	if err != nil {
+		return nil, &WriteError{Path: fname, Err: err}
+	}
+	_, err = fmt.Fprintf(f, "%d\n", os.Getpid())
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(fname)
+		return nil, &WriteError{Path: fname, Err: err}
+	}
+	return func() {
+		os.Remove(fname)
+	}, nil
+}
@@ -2,9 +2,13 @@ package main
 
 import (
 	"bytes"
+	"flag"
 	"fmt"
+	"io"
+	"math/rand"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/arcana261/lifeinuk/sliceutils"
 )
@@ -18,58 +22,165 @@ const (
 
 	alignmentWidth    = 60
 	puzzleChoiceCount = 4
+
+	highlightsFile = "data/highlights.txt"
+	phrasesFile    = "data/phrases.txt"
+	aliasesFile    = "data/aliases.txt"
+	scoresFile     = "scores.txt"
+)
+
+var (
+	goalNew     = flag.Int("new", 0, "daily goal of new highlights, further new highlights are held back once reached")
+	goalReviews = flag.Int("reviews", 0, "daily goal of reviewed highlights")
+	goalMinutes = flag.Int("minutes", 0, "daily goal of minutes spent studying")
+	seed        = flag.Int64("seed", 0, "seed of the random source, 0 picks one from the clock; pass the seed from a session summary to replay it")
 )
 
 func main() {
-	highlights, err := ReadHighlights("data/highlights.txt", "scores.txt")
+	flag.Parse()
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+	var err error
+	store, err = openStore(*storeKind, *profile)
 	if err != nil {
-		panic(err)
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(1)
 	}
-	for i := 0; i < len(highlights.Highlights); i++ {
-		highlights.Highlights[i].Content = fixAlignment(highlights.Highlights[i].Content, alignmentWidth)
+	defer store.Close()
+	if flag.NArg() > 0 {
+		if err := runCommand(flag.Args()); err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			os.Exit(1)
+		}
+		return
 	}
-	copyFile("data/highlights.txt", "highlights.txt.bak")
-	WriteHighlights(highlights, "data/highlights.txt")
 
-	for {
-		fmt.Printf("\n")
-		fmt.Printf("  1. Print Random Card\n")
-		fmt.Printf("  2. Fill Card Game\n")
-		fmt.Printf("  Q. Quit\n")
-		fmt.Printf("\n")
-
-		cmd := strings.ToLower(readOne())
-		switch cmd {
-		case "q":
+	if err := study(); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(1)
+	}
+}
+
+func study() (err error) {
+	unlock, err := lockData()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	highlights, err := loadAligned()
+	if err != nil {
+		return err
+	}
+
+	daily, err := readDaily(profilePath(*profile, dailyFile), time.Now())
+	if err != nil {
+		return err
+	}
+	goal := sessionGoal{New: *goalNew, Reviews: *goalReviews, Minutes: *goalMinutes}
+	sess := newSession(goal, daily, *seed, time.Now())
+	highlights.Eligible = sess.Eligible
+	defer func() {
+		now := time.Now()
+		daily := sess.Finish(now)
+		if writeErr := writeDaily(profilePath(*profile, dailyFile), daily); err == nil {
+			err = writeErr
+		}
+		sess.PrintSummary(now, daily)
+	}()
+	defer func() {
+		if err != errEndOfInput {
 			return
+		}
+		err = saveScores(highlights)
+		if err == nil {
+			fmt.Fprintf(stdout, "\nEnd of input, scores saved.\n")
+		}
+	}()
+
+	before := snapshotScores(highlights)
+	if err := resumeCheckpoint(highlights); err != nil {
+		return err
+	}
+	sess.Observe(before, highlights)
+
+	metAtStart := sess.GoalMet(time.Now())
+	for metAtStart || !sess.GoalMet(time.Now()) {
+		fmt.Fprintf(stdout, "\n")
+		fmt.Fprintf(stdout, "  1. Print Random Card\n")
+		fmt.Fprintf(stdout, "  2. Fill Card Game\n")
+		fmt.Fprintf(stdout, "  3. Scramble Card Game\n")
+		fmt.Fprintf(stdout, "  4. True or False Game\n")
+		fmt.Fprintf(stdout, "  5. Question Game\n")
+		fmt.Fprintf(stdout, "  6. Exam Readiness\n")
+		fmt.Fprintf(stdout, "  Q. Quit\n")
+		fmt.Fprintf(stdout, "\n")
+
+		before := snapshotScores(highlights)
+
+		key, err := readOne()
+		if err != nil {
+			return err
+		}
+		switch strings.ToLower(key) {
+		case "q":
+			return nil
 		case "1":
 			printRandomCard(highlights)
 		case "2":
-			fillCard(highlights)
+			err = fillCard(highlights)
+		case "3":
+			err = scrambleCard(highlights)
+		case "4":
+			err = trueFalseCard(highlights)
+		case "5":
+			err = questionCard(highlights)
+		case "6":
+			printReadiness(estimateReadiness(highlights, readinessRuns))
 		default:
 		}
+
+		sess.Observe(before, highlights)
+		if err != nil {
+			return err
+		}
 	}
+
+	fmt.Fprintf(stdout, "%sDAILY GOAL REACHED!%s\n", colorGreen, colorNone)
+	return nil
 }
 
-func fillCard(highlights HighlightDatabase) {
+func fillCard(highlights HighlightDatabase) error {
 	h := highlights.PickHighlight()
-	hContent := []rune(h.Content)
+	if h == nil {
+		return nil
+	}
+	if err := showLeech(h); err != nil {
+		return err
+	}
+	return playFillCard(highlights, h, newFillProgress(h))
+}
 
-	correctAnswers := 0
-	wrongAnswers := 0
-	lastI := -1
-	var previousWrongs []int
+func playFillCard(highlights HighlightDatabase, h *Highlight, p fillProgress) error {
+	hContent := []rune(h.Content)
+	var history []fillProgress
 
-	for i := 2; i < len(h.Tokens); i++ {
+tokens:
+	for i := p.Index; i < len(h.Tokens); i++ {
 		currentToken := highlights.TokenMap[h.Tokens[i]]
 
 		if currentToken.SkipPuzzle {
 			continue
 		}
+		if p.Index != i {
+			p.Index = i
+			p.Hints = 0
+		}
 
 		var skips []string
 		skips = append(skips, currentToken.Content)
-		for _, w := range previousWrongs {
+		for _, w := range p.PreviousWrongs {
 			skips = append(skips, highlights.TokenMap[w].Content)
 		}
 
@@ -113,19 +224,26 @@ func fillCard(highlights HighlightDatabase) {
 			puzzleChoiceCount-1,
 			skips...,
 		)
-		sliceutils.Permutate(previousWrongs)
-		for j := 0; j < len(previousWrongs) && len(nextTokens) < puzzleChoiceCount-1; j++ {
-			nextTokens = append(nextTokens, previousWrongs[j])
+		sliceutils.Permutate(highlights.Rand, p.PreviousWrongs)
+		for j := 0; j < len(p.PreviousWrongs) && len(nextTokens) < puzzleChoiceCount-1; j++ {
+			nextTokens = append(nextTokens, p.PreviousWrongs[j])
 		}
 		if len(nextTokens) < 1 {
 			continue
 		}
 		nextTokens = append(nextTokens, h.Tokens[i])
-		sliceutils.Permutate(nextTokens)
+		sliceutils.Permutate(highlights.Rand, nextTokens)
+		if p.Hints >= maxHints {
+			nextTokens = eliminateDistractor(highlights.Rand, nextTokens, h.Tokens[i])
+		}
 
 		next := -1
 
 		for next < 0 || next >= len(nextTokens) {
+			if err := saveCheckpoint(profilePath(*profile, checkpointFile), p); err != nil {
+				return err
+			}
+
 			var lineToPrint bytes.Buffer
 
 			lineToPrint.WriteString("\n> ")
@@ -139,12 +257,12 @@ func fillCard(highlights HighlightDatabase) {
 					}
 				}*/
 
-			if lastI > 0 {
-				lineToPrint.WriteString(string(hContent[:h.TokenStarts[lastI]]))
+			if p.LastIndex > 0 {
+				lineToPrint.WriteString(string(hContent[:h.TokenStarts[p.LastIndex]]))
 				lineToPrint.WriteString(colorGreen)
-				lineToPrint.WriteString(string(hContent[h.TokenStarts[lastI]:h.TokenStarts[lastI+1]]))
+				lineToPrint.WriteString(string(hContent[h.TokenStarts[p.LastIndex]:h.TokenStarts[p.LastIndex+1]]))
 				lineToPrint.WriteString(colorNone)
-				lineToPrint.WriteString(strings.TrimSuffix(string(hContent[h.TokenStarts[lastI+1]:h.TokenStarts[i]]), " "))
+				lineToPrint.WriteString(strings.TrimSuffix(string(hContent[h.TokenStarts[p.LastIndex+1]:h.TokenStarts[i]]), " "))
 			} else {
 				lineToPrint.WriteString(strings.TrimSuffix(string(hContent[:h.TokenStarts[i]]), " "))
 			}
@@ -157,23 +275,57 @@ func fillCard(highlights HighlightDatabase) {
 			)
 			content := fixAlignment(replacer.Replace(lineToPrint.String()), alignmentWidth)
 
-			fmt.Printf("\n%s\n\n", content)
+			fmt.Fprintf(stdout, "\n%s\n\n", content)
+			if p.Hints > 0 {
+				hint := tokenHint(string(hContent[h.TokenStarts[i]:h.TokenEnds[i]]), p.Hints)
+				fmt.Fprintf(stdout, "  %sHint: %s%s\n\n", colorBlue, hint, colorNone)
+			}
 
 			for j := 0; j < len(nextTokens); j++ {
-				txt := highlights.TokenMap[nextTokens[j]].RealContent
-				if len(txt) > 0 {
-					rtxt := []rune(txt)
-					txt = fmt.Sprintf("%s%s", strings.ToUpper(string(rtxt[:1])), string(rtxt[1:]))
-				}
-				fmt.Printf("  %d. %s\n", (j + 1), txt)
+				txt := capitalize(highlights.TokenMap[nextTokens[j]].RealContent)
+				fmt.Fprintf(stdout, "  %d. %s\n", (j + 1), txt)
+			}
+			if p.Hints < maxHints {
+				fmt.Fprintf(stdout, "  H. Hint\n")
+			}
+			if len(history) > 0 {
+				fmt.Fprintf(stdout, "  U. Undo\n")
 			}
-			fmt.Printf("  Q. Quit\n")
-			fmt.Printf("\n")
+			fmt.Fprintf(stdout, "  E. Edit\n")
+			fmt.Fprintf(stdout, "  Q. Quit\n")
+			fmt.Fprintf(stdout, "\n")
 
-			cmd := strings.ToLower(readOne())
-			switch cmd {
+			key, err := readOne()
+			if err != nil {
+				return err
+			}
+			switch strings.ToLower(key) {
 			case "q":
-				return
+				fmt.Fprintf(stdout, "Progress saved, the card will resume on next start.\n")
+				return nil
+			case "h":
+				if p.Hints < maxHints {
+					p.Hints = p.Hints + 1
+					if p.Hints == maxHints {
+						nextTokens = eliminateDistractor(highlights.Rand, nextTokens, h.Tokens[i])
+					}
+				}
+			case "e":
+				changed, err := editHighlight(highlights, h)
+				if err != nil {
+					return err
+				}
+				if changed {
+					return playFillCard(highlights, h, newFillProgress(h))
+				}
+			case "u":
+				if len(history) > 0 {
+					p = history[len(history)-1]
+					history = history[:len(history)-1]
+					fmt.Fprintf(stdout, "%sUNDONE%s\n", colorBlue, colorNone)
+					i = p.Index - 1
+					continue tokens
+				}
 			case "1":
 				next = 0
 			case "2":
@@ -185,32 +337,30 @@ func fillCard(highlights HighlightDatabase) {
 			}
 		}
 
+		history = append(history, p.Clone())
 		selected := nextTokens[next]
 		if h.Tokens[i] == selected {
-			fmt.Fprintf(os.Stdout, "%sCORRECT!%s\n", colorGreen, colorNone)
-			correctAnswers = correctAnswers + 1
-			lastI = i
-			previousWrongs = nil
+			fmt.Fprintf(stdout, "%sCORRECT!%s\n", colorGreen, colorNone)
+			p.CorrectAnswers = p.CorrectAnswers + 1
+			p.Credit = p.Credit + hintCredit(p.Hints)
+			p.LastIndex = i
+			p.PreviousWrongs = nil
 		} else {
-			previousWrongs = append(previousWrongs, selected)
+			p.PreviousWrongs = append(p.PreviousWrongs, selected)
 
-			txt := highlights.TokenMap[selected].RealContent
-			if len(txt) > 0 {
-				txt = fmt.Sprintf("%s%s", strings.ToUpper(txt[:1]), txt[1:])
-			}
+			txt := capitalize(highlights.TokenMap[selected].RealContent)
 
-			fmt.Fprintf(os.Stdout, "%sWRONG: %s%s\n", colorRed, txt, colorNone)
-			wrongAnswers = wrongAnswers + 1
+			fmt.Fprintf(stdout, "%sWRONG: %s%s\n", colorRed, txt, colorNone)
+			p.WrongAnswers = p.WrongAnswers + 1
 			i = i - 1
 		}
 	}
 
-	totalAnswers := max(1, correctAnswers+wrongAnswers)
-	h.Score.Count = h.Score.Count + 1
-
-	score := float64(correctAnswers) / float64(totalAnswers)
-	score = score * float64(h.Score.Count)
-	h.Score.Sum = h.Score.Sum + score
+	h.RecordRatio(p.Credit / float64(max(1, p.CorrectAnswers+p.WrongAnswers)))
+	if err := removeCheckpoint(profilePath(*profile, checkpointFile)); err != nil {
+		return err
+	}
+	lastI := p.LastIndex
 
 	if lastI > 0 {
 		var lineToPrint bytes.Buffer
@@ -227,47 +377,124 @@ func fillCard(highlights HighlightDatabase) {
 			lineToPrint.WriteString(string(hContent[h.TokenStarts[lastI+1]:]))
 		}
 
-		replacer := strings.NewReplacer(
-			"\n", " ",
-			"\r", " ",
-			"\t", " ",
-		)
-		content := fixAlignment(replacer.Replace(lineToPrint.String()), alignmentWidth)
-
-		fmt.Printf("\n%s\n\n", content)
+		printContent(lineToPrint.String())
 	} else {
-		replacer := strings.NewReplacer(
-			"\n", " ",
-			"\r", " ",
-			"\t", " ",
-		)
-		content := fixAlignment(replacer.Replace(h.Content), alignmentWidth)
+		printContent(h.Content)
+	}
+
+	if err := saveScores(highlights); err != nil {
+		return err
+	}
+	return flagPrompt(highlights, h)
+}
 
-		fmt.Printf("\n%s\n\n", content)
+// loadAligned loads the deck and rewraps every highlight, saving the deck
+// if that changed it.
+func loadAligned() (HighlightDatabase, error) {
+	unlock, err := lockDeck()
+	if err != nil {
+		return HighlightDatabase{}, err
 	}
+	defer unlock()
 
-	if fileExists("scores.txt") {
-		copyFile("scores.txt", "scores.txt.bak")
+	highlights, err := loadHighlights()
+	if err != nil {
+		return HighlightDatabase{}, err
+	}
+	for i := 0; i < len(highlights.Highlights); i++ {
+		if highlights.Highlights[i].Parent != "" {
+			continue
+		}
+		highlights.Highlights[i].Content = fixAlignment(highlights.Highlights[i].Content, alignmentWidth)
 	}
-	highlights.WriteScore("scores.txt")
+	for _, source := range highlights.Sources {
+		source.Content = fixAlignment(source.Content, alignmentWidth)
+	}
+	if err := WriteHighlights(highlights, store); err != nil {
+		return HighlightDatabase{}, err
+	}
+	return highlights, nil
+}
+
+func saveScores(highlights HighlightDatabase) error {
+	if err := store.WriteScores(highlights.Scores()); err != nil {
+		return err
+	}
+	if err := appendReviews(profilePath(*profile, reviewsFile), pendingReviews); err != nil {
+		return err
+	}
+	pendingReviews = nil
+	return nil
+}
+
+func loadHighlights() (HighlightDatabase, error) {
+	highlights, err := ReadHighlights(store, phrasesFile, aliasesFile)
+	if err != nil {
+		return HighlightDatabase{}, err
+	}
+	highlights.Rand = rand.New(rand.NewSource(*seed))
+	return splitHighlights(highlights, *splitTokens), nil
+}
+
+func printContent(content string) {
+	replacer := strings.NewReplacer(
+		"\n", " ",
+		"\r", " ",
+		"\t", " ",
+	)
+	fmt.Fprintf(stdout, "\n%s\n\n", fixAlignment(replacer.Replace(content), alignmentWidth))
 }
 
 func printRandomCard(highlights HighlightDatabase) {
 	h := highlights.PickHighlight()
-	fmt.Printf("%s\n", h.Content)
+	if h == nil {
+		return
+	}
+	fmt.Fprintf(stdout, "%s\n", h.Content)
 }
 
-func readOne() string {
+// readLine reads the next non-empty line, errEndOfInput when stdin ends.
+func readLine() (string, error) {
+	var line bytes.Buffer
 	for {
 		buff := make([]byte, 1)
-		n, err := os.Stdin.Read(buff)
+		n, err := stdin.Read(buff)
+		if err == io.EOF {
+			return "", errEndOfInput
+		}
+		if err != nil {
+			return "", &InputError{Err: err}
+		}
+		if n == 1 {
+			if buff[0] == '\n' {
+				str := strings.TrimSpace(line.String())
+				if str != "" {
+					return str, nil
+				}
+				line.Reset()
+				continue
+			}
+			line.WriteByte(buff[0])
+		}
+	}
+}
+
+// readOne reads the next key that is not white space, errEndOfInput when
+// stdin ends.
+func readOne() (string, error) {
+	for {
+		buff := make([]byte, 1)
+		n, err := stdin.Read(buff)
+		if err == io.EOF {
+			return "", errEndOfInput
+		}
 		if err != nil {
-			panic(err)
+			return "", &InputError{Err: err}
 		}
 		if n == 1 {
 			str := strings.TrimSpace(string(buff))
 			if str != "" {
-				return str
+				return str, nil
 			}
 		}
 	}
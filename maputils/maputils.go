@@ -10,6 +10,14 @@ func ToEntries[E ~map[K]V, K comparable, V any](s E) []sliceutils.Pair[K, V] {
 	return result
 }
 
+func Keys[E ~map[K]V, K comparable, V any](s E) []K {
+	var result []K
+	for k := range s {
+		result = append(result, k)
+	}
+	return result
+}
+
 func FromEntries[E ~[]sliceutils.Pair[K, V], K comparable, V any](s E) map[K]V {
 	return sliceutils.ToMapFunc(s, func(p sliceutils.Pair[K, V]) (K, V) {
 		return p.Key, p.Value
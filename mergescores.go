@@ -0,0 +1,143 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/arcana261/lifeinuk/maputils"
+	"github.com/arcana261/lifeinuk/sliceutils"
+)
+
+// mergeScore adds the reviews theirs made since base on top of ours. Sum
+// weighs every review by its position, so their reviews are moved to the
+// positions after ours using their mean ratio; the exact ratios are not
+// recoverable from a sum.
+func mergeScore(base, ours, theirs Score) Score {
+	result := ours
+	result.Flags = sliceutils.Clone(ours.Flags)
+
+	if n := theirs.Count - base.Count; n > 0 {
+		positions := func(from int) float64 {
+			return float64(n*from) + float64(n*(n+1))/2
+		}
+		mean := (theirs.Sum - base.Sum) / positions(base.Count)
+		result.Sum = result.Sum + mean*positions(ours.Count)
+		result.Count = result.Count + n
+	}
+	result.Lapses = result.Lapses + max(0, theirs.Lapses-base.Lapses)
+
+	for _, f := range theirs.Flags {
+		if !base.HasFlag(f) {
+			result.SetFlag(f)
+		}
+	}
+	for _, f := range base.Flags {
+		if !theirs.HasFlag(f) {
+			result.ClearFlag(f)
+		}
+	}
+	if until := latestBuried(result.Flags); until != "" {
+		result.Bury(until)
+	}
+
+	result.Reviewed = max(ours.Reviewed, theirs.Reviewed)
+	result.Extra = mergeExtra(base.Extra, ours.Extra, theirs.Extra)
+	result.Average = result.WeightedAverage()
+	return result
+}
+
+func latestBuried(flags []string) string {
+	var result string
+	for _, f := range flags {
+		if strings.HasPrefix(f, flagBuriedPrefix) {
+			result = max(result, strings.TrimPrefix(f, flagBuriedPrefix))
+		}
+	}
+	return result
+}
+
+func mergeExtra(base, ours, theirs []string) []string {
+	key := func(field string) string {
+		k, _, _ := strings.Cut(field, "=")
+		return k
+	}
+	result := sliceutils.Clone(ours)
+	for _, field := range theirs {
+		if sliceutils.Contains(base, field) {
+			continue
+		}
+		at := sliceutils.IndexOfFunc(result, func(f string) bool {
+			return key(f) == key(field)
+		})
+		if at < 0 {
+			result = append(result, field)
+		} else {
+			result[at] = field
+		}
+	}
+	return result
+}
+
+// mergeScores folds every side into the first one against the same base; an
+// ID missing from a side counts as unchanged since the base.
+func mergeScores(base map[string]Score, sides []map[string]Score) map[string]Score {
+	result := maputils.MapFunc(sides[0], func(id string, s Score) (string, Score) {
+		return id, s
+	})
+	for id, s := range base {
+		if _, ok := result[id]; !ok {
+			result[id] = s
+		}
+	}
+	for _, side := range sides[1:] {
+		for id, theirs := range side {
+			result[id] = mergeScore(base[id], result[id], theirs)
+		}
+	}
+	return result
+}
+
+func cmdMergeScores(args []string) error {
+	fs := flag.NewFlagSet("merge-scores", flag.ContinueOnError)
+	base := fs.String("base", "", "common ancestor of the files; without it every review in every file is counted")
+	output := fs.String("o", "", "write the merged scores to this file instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() < 2 {
+		return fmt.Errorf("usage: merge-scores [-base FILE] [-o FILE] FILE FILE...")
+	}
+
+	// The merged file keeps the newest version among the inputs.
+	version := scoresVersion
+	var baseScores map[string]Score
+	if *base != "" {
+		var err error
+		var v int
+		baseScores, v, err = readScores(*base)
+		if err != nil {
+			return err
+		}
+		version = max(version, v)
+	}
+	var sides []map[string]Score
+	for _, fname := range fs.Args() {
+		if !fileExists(fname) {
+			return fmt.Errorf("%s not found", fname)
+		}
+		scores, v, err := readScores(fname)
+		if err != nil {
+			return err
+		}
+		version = max(version, v)
+		sides = append(sides, scores)
+	}
+
+	merged := formatScores(mergeScores(baseScores, sides), version)
+	if *output == "" {
+		fmt.Fprint(stdout, merged)
+		return nil
+	}
+	return writeFileAtomic(*output, []byte(merged))
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"unicode"
+
+	"github.com/arcana261/lifeinuk/sliceutils"
+)
+
+const (
+	phraseMinOccurrences = 2
+	phraseMaxLength      = 6
+)
+
+var phraseConnectors = map[string]bool{
+	"of":   true,
+	"the":  true,
+	"and":  true,
+	"for":  true,
+	"upon": true,
+	"de":   true,
+}
+
+var phraseStopWords = map[string]bool{
+	"the":    true,
+	"a":      true,
+	"an":     true,
+	"in":     true,
+	"on":     true,
+	"at":     true,
+	"it":     true,
+	"this":   true,
+	"these":  true,
+	"there":  true,
+	"he":     true,
+	"she":    true,
+	"they":   true,
+	"we":     true,
+	"you":    true,
+	"i":      true,
+	"after":  true,
+	"before": true,
+	"during": true,
+	"when":   true,
+	"if":     true,
+}
+
+// phraseSet indexes phrases by their first token, longest phrase first, so
+// that merging always prefers "house of commons" over "house of".
+type phraseSet map[string][][]string
+
+func newPhraseSet(phrases [][]string) phraseSet {
+	result := make(phraseSet)
+	for _, phrase := range phrases {
+		if len(phrase) < 2 {
+			continue
+		}
+		key := strings.Join(phrase, " ")
+		if sliceutils.ContainsFunc(result[phrase[0]], func(p []string) bool {
+			return strings.Join(p, " ") == key
+		}) {
+			continue
+		}
+		result[phrase[0]] = append(result[phrase[0]], phrase)
+	}
+	for first, candidates := range result {
+		result[first] = sliceutils.SortFunc(candidates, func(a, b []string) int {
+			return len(b) - len(a)
+		})
+	}
+	return result
+}
+
+func readPhrases(fname string) ([][]string, error) {
+	if !fileExists(fname) {
+		return nil, nil
+	}
+
+	b, err := os.ReadFile(fname)
+	if err != nil {
+		return nil, err
+	}
+
+	var result [][]string
+	for _, line := range sliceutils.TrimSpace(strings.Split(string(b), "\n")) {
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		tokens := sliceutils.MapFunc(defaultTokenizer.Tokenize(line), func(x ParsedToken) string {
+			return x.Content
+		})
+		if len(tokens) > 1 {
+			result = append(result, tokens)
+		}
+	}
+	return result, nil
+}
+
+func detectPhrases(entries []string, entryTokens [][]ParsedToken) [][]string {
+	counts := make(map[string]int)
+	var order [][]string
+
+	for index, tokens := range entryTokens {
+		input := []rune(entries[index])
+
+		for i := 0; i < len(tokens); i++ {
+			if !isCapitalizedToken(tokens[i]) || phraseStopWords[tokens[i].Content] {
+				continue
+			}
+
+			j := i + 1
+			for j < len(tokens) && j-i < phraseMaxLength && isWhitespaceGap(input, tokens[j-1], tokens[j]) {
+				if isCapitalizedToken(tokens[j]) {
+					j = j + 1
+					continue
+				}
+				if phraseConnectors[tokens[j].Content] && j+1 < len(tokens) && j+1-i < phraseMaxLength &&
+					isWhitespaceGap(input, tokens[j], tokens[j+1]) && isCapitalizedToken(tokens[j+1]) {
+					j = j + 2
+					continue
+				}
+				break
+			}
+			if j-i < 2 {
+				continue
+			}
+
+			phrase := sliceutils.MapFunc(tokens[i:j], func(x ParsedToken) string {
+				return x.Content
+			})
+			key := strings.Join(phrase, " ")
+			if counts[key] == 0 {
+				order = append(order, phrase)
+			}
+			counts[key] = counts[key] + 1
+			i = j - 1
+		}
+	}
+
+	return sliceutils.FilterFunc(order, func(phrase []string) bool {
+		return counts[strings.Join(phrase, " ")] >= phraseMinOccurrences
+	})
+}
+
+// mergePhrases joins consecutive tokens matching a phrase into one token.
+// Contents are joined by a single space, which keeps generateID stable for
+// highlights whose scores were recorded before the phrase was known, and so
+// is the real text, which may be broken across lines.
+func mergePhrases(entry string, tokens []ParsedToken, phrases phraseSet) []ParsedToken {
+	if len(phrases) == 0 {
+		return tokens
+	}
+
+	input := []rune(entry)
+	var result []ParsedToken
+
+	for i := 0; i < len(tokens); i++ {
+		matched := 0
+		for _, phrase := range phrases[tokens[i].Content] {
+			if matchesPhrase(input, tokens[i:], phrase) {
+				matched = len(phrase)
+				break
+			}
+		}
+		if matched == 0 {
+			result = append(result, tokens[i])
+			continue
+		}
+
+		first := tokens[i]
+		last := tokens[i+matched-1]
+		result = append(result, ParsedToken{
+			Content: strings.Join(sliceutils.MapFunc(tokens[i:i+matched], func(x ParsedToken) string {
+				return x.Content
+			}), " "),
+			Start:       first.Start,
+			End:         last.End,
+			RealContent: strings.Join(strings.Fields(string(input[first.Start:last.End])), " "),
+		})
+		i = i + matched - 1
+	}
+
+	return result
+}
+
+func matchesPhrase(input []rune, tokens []ParsedToken, phrase []string) bool {
+	if len(tokens) < len(phrase) {
+		return false
+	}
+	for k := 0; k < len(phrase); k++ {
+		if tokens[k].Content != phrase[k] {
+			return false
+		}
+		if k > 0 && !isWhitespaceGap(input, tokens[k-1], tokens[k]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isWhitespaceGap(input []rune, prev ParsedToken, next ParsedToken) bool {
+	if prev.End > next.Start || next.Start > len(input) {
+		return false
+	}
+	for _, r := range input[prev.End:next.Start] {
+		if !unicode.IsSpace(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func isCapitalizedToken(t ParsedToken) bool {
+	if strings.Contains(t.Content, " ") {
+		return false
+	}
+	rs := []rune(t.RealContent)
+	return len(rs) > 0 && unicode.IsUpper(rs[0])
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/arcana261/lifeinuk/sliceutils"
+)
+
+const (
+	profilesDir     = "data/profiles"
+	masteredAverage = 0.8
+	leaderboardRuns = 2000
+)
+
+var profile = flag.String("profile", "", "study as this profile, with its own scores, daily progress and checkpoint under data/profiles")
+
+// profilePath places per-person files of a named profile in its own
+// directory next to the shared deck; the default profile keeps them in the
+// working directory as before.
+func profilePath(name string, fname string) string {
+	if name == "" {
+		return fname
+	}
+	return filepath.Join(profilesDir, name, fname)
+}
+
+func checkProfile(name string) error {
+	if name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+		return fmt.Errorf("invalid profile name %q", name)
+	}
+	return nil
+}
+
+func listProfiles() ([]string, error) {
+	result := []string{""}
+	entries, err := os.ReadDir(profilesDir)
+	if os.IsNotExist(err) {
+		return result, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() && checkProfile(entry.Name()) == nil {
+			result = append(result, entry.Name())
+		}
+	}
+	return result, nil
+}
+
+func profileName(name string) string {
+	if name == "" {
+		return "(default)"
+	}
+	return name
+}
+
+type profileStanding struct {
+	Name     string
+	Seen     int
+	Mastered int
+	Average  float64
+	Pass     float64
+	Streak   int
+	Best     int
+}
+
+func standing(highlights HighlightDatabase, name string, scores map[string]Score, now time.Time) (profileStanding, error) {
+	result := profileStanding{Name: profileName(name)}
+	db := highlights
+	db.Highlights = sliceutils.Clone(highlights.Highlights)
+	today := now.Format(dayFormat)
+	for i := range db.Highlights {
+		h := &db.Highlights[i]
+		h.Score = normalizeScore(scores[h.ID], today)
+		result.Average = result.Average + h.Score.WeightedAverage()
+		if h.Score.Count > 0 {
+			result.Seen = result.Seen + 1
+		}
+		if h.Score.WeightedAverage() >= masteredAverage {
+			result.Mastered = result.Mastered + 1
+		}
+	}
+	result.Average = result.Average / float64(max(1, len(db.Highlights)))
+	result.Pass = estimateReadiness(db, leaderboardRuns).Pass
+
+	daily, err := readDaily(profilePath(name, dailyFile), now)
+	if err != nil {
+		return profileStanding{}, err
+	}
+	result.Streak = daily.Streak
+	result.Best = daily.Best
+	return result, nil
+}
+
+func cmdProfiles(args []string) error {
+	fs := flag.NewFlagSet("profiles", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	highlights, err := loadHighlights()
+	if err != nil {
+		return err
+	}
+	names, err := listProfiles()
+	if err != nil {
+		return err
+	}
+	aliases, err := readAliases(aliasesFile)
+	if err != nil {
+		return err
+	}
+
+	var standings []profileStanding
+	for _, name := range names {
+		s, err := openStore(*storeKind, name)
+		if err != nil {
+			return err
+		}
+		scores, err := s.ReadScores()
+		s.Close()
+		if err != nil {
+			return err
+		}
+		if name != "" && len(scores) == 0 {
+			continue
+		}
+		st, err := standing(highlights, name, resolveScores(scores, aliases), time.Now())
+		if err != nil {
+			return err
+		}
+		standings = append(standings, st)
+	}
+	slices.SortFunc(standings, func(x, y profileStanding) int {
+		return -CompareFloat64(x.Average, y.Average)
+	})
+
+	fmt.Fprintf(stdout, "\n  Leaderboard over %d highlights\n\n", len(highlights.Highlights))
+	fmt.Fprintf(stdout, "  %-3s %-16s %6s %9s %8s %6s %8s\n", "", "Profile", "Seen", "Mastered", "Average", "Pass", "Streak")
+	for i, st := range standings {
+		color := colorNone
+		if st.Name == profileName(*profile) {
+			color = colorGreen
+		}
+		fmt.Fprintf(stdout, "  %s%-3s %-16s %6d %9d %7.0f%% %5.0f%% %4d/%-3d%s\n", color, fmt.Sprintf("%d.", i+1), st.Name, st.Seen, st.Mastered, st.Average*100, st.Pass*100, st.Streak, st.Best, colorNone)
+	}
+	fmt.Fprintf(stdout, "\n")
+	return nil
+}
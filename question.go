@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/arcana261/lifeinuk/sliceutils"
+)
+
+const (
+	questionPrefix = "Q:"
+	sourcePrefix   = "Source:"
+	questionBlank  = "_____"
+)
+
+var selfGrades = []sliceutils.Pair[string, float64]{
+	{Key: "Again", Value: 0},
+	{Key: "Hard", Value: 0.5},
+	{Key: "Good", Value: 0.8},
+	{Key: "Easy", Value: 1},
+}
+
+// splitQuestions separates leading "Q:" and "Source:" lines from each entry
+// so that neither takes part in tokenization or generateID.
+func splitQuestions(entries []string) ([]string, []string, []string) {
+	var contents []string
+	var questions []string
+	var sources []string
+	for _, entry := range entries {
+		lines := strings.Split(entry, "\n")
+		var question []string
+		var source []string
+		for len(lines) > 0 {
+			line := strings.TrimSpace(lines[0])
+			if strings.HasPrefix(line, questionPrefix) {
+				question = append(question, strings.TrimSpace(strings.TrimPrefix(line, questionPrefix)))
+			} else if strings.HasPrefix(line, sourcePrefix) {
+				source = append(source, strings.TrimSpace(strings.TrimPrefix(line, sourcePrefix)))
+			} else {
+				break
+			}
+			lines = lines[1:]
+		}
+		content := strings.TrimSpace(strings.Join(lines, "\n"))
+		if content == "" {
+			continue
+		}
+		contents = append(contents, content)
+		questions = append(questions, strings.Join(question, " "))
+		sources = append(sources, strings.Join(source, " "))
+	}
+	return contents, questions, sources
+}
+
+// entryText puts the "Source:" and "Q:" lines back in front of the content.
+func entryText(content string, question string, source string) string {
+	var buff bytes.Buffer
+	if source != "" {
+		fmt.Fprintf(&buff, "%s %s\n", sourcePrefix, source)
+	}
+	if question != "" {
+		fmt.Fprintf(&buff, "%s %s\n", questionPrefix, question)
+	}
+	buff.WriteString(content)
+	return buff.String()
+}
+
+func tokenRank(highlights HighlightDatabase, h *Highlight, i int) int {
+	switch highlightTokenClass(highlights, h, i) {
+	case tokenClassYear:
+		return 3
+	case tokenClassName:
+		return 2
+	case tokenClassNumber:
+		return 1
+	}
+	return 0
+}
+
+// informativeTokens returns the indexes of the quizzable tokens of h from
+// index from on, years first, then names, then numbers, rarer tokens before
+// common ones.
+func informativeTokens(highlights HighlightDatabase, h *Highlight, from int) []int {
+	items := sliceutils.FilterFunc(sliceutils.Range(min(from, len(h.Tokens)), len(h.Tokens)), func(i int) bool {
+		return !highlights.TokenMap[h.Tokens[i]].SkipPuzzle
+	})
+	return sliceutils.SortFunc(items, func(a, b int) int {
+		if c := tokenRank(highlights, h, b) - tokenRank(highlights, h, a); c != 0 {
+			return c
+		}
+		if c := highlights.TokenMap[h.Tokens[a]].AppearInNextToken - highlights.TokenMap[h.Tokens[b]].AppearInNextToken; c != 0 {
+			return c
+		}
+		return a - b
+	})
+}
+
+func informativeToken(highlights HighlightDatabase, h *Highlight) int {
+	items := informativeTokens(highlights, h, 0)
+	if len(items) == 0 {
+		return -1
+	}
+	return items[0]
+}
+
+func questionCard(highlights HighlightDatabase) error {
+	h := highlights.PickHighlight()
+	if h == nil {
+		return nil
+	}
+	if err := showLeech(h); err != nil {
+		return err
+	}
+	hContent := []rune(h.Content)
+
+	question := h.Question
+	blank := -1
+	if question == "" {
+		blank = informativeToken(highlights, h)
+		if blank < 0 {
+			printContent(h.Content)
+			return nil
+		}
+		question = string(hContent[:h.TokenStarts[blank]]) + questionBlank + string(hContent[h.TokenEnds[blank]:])
+	}
+
+	printContent(fmt.Sprintf("> %s", question))
+	fmt.Fprintf(stdout, "Type your answer and press enter:\n\n")
+	answer, err := readLine()
+	if err != nil {
+		return err
+	}
+
+	if blank >= 0 {
+		var lineToPrint bytes.Buffer
+		lineToPrint.WriteString(string(hContent[:h.TokenStarts[blank]]))
+		lineToPrint.WriteString(colorGreen)
+		lineToPrint.WriteString(string(hContent[h.TokenStarts[blank]:h.TokenEnds[blank]]))
+		lineToPrint.WriteString(colorNone)
+		lineToPrint.WriteString(string(hContent[h.TokenEnds[blank]:]))
+		printContent(lineToPrint.String())
+
+		expected := highlights.TokenMap[h.Tokens[blank]].Content
+		given := strings.Join(sliceutils.MapFunc(defaultTokenizer.Tokenize(answer), func(x ParsedToken) string {
+			return x.Content
+		}), " ")
+		if given == expected {
+			fmt.Fprintf(stdout, "%sYOUR ANSWER MATCHES!%s\n", colorGreen, colorNone)
+		} else {
+			fmt.Fprintf(stdout, "%sYOUR ANSWER: %s%s\n", colorYellow, answer, colorNone)
+		}
+	} else {
+		printContent(h.Content)
+		fmt.Fprintf(stdout, "%sYOUR ANSWER: %s%s\n", colorYellow, answer, colorNone)
+	}
+
+	grade := -1
+	for grade < 0 {
+		fmt.Fprintf(stdout, "\n")
+		for j, g := range selfGrades {
+			fmt.Fprintf(stdout, "  %d. %s\n", (j + 1), g.Key)
+		}
+		fmt.Fprintf(stdout, "  Q. Quit\n")
+		fmt.Fprintf(stdout, "\n")
+
+		key, err := readOne()
+		if err != nil {
+			return err
+		}
+		cmd := strings.ToLower(key)
+		if cmd == "q" {
+			return nil
+		}
+		for j := range selfGrades {
+			if cmd == fmt.Sprintf("%d", j+1) {
+				grade = j
+			}
+		}
+	}
+
+	h.RecordRatio(selfGrades[grade].Value)
+	return saveScores(highlights)
+}
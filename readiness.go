@@ -0,0 +1,192 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/arcana261/lifeinuk/sliceutils"
+)
+
+const (
+	examQuestions = 24
+	examPassMark  = 18
+	examGuessRate = 0.25
+
+	readinessRuns         = 2000
+	readinessSuggestions  = 10
+	readinessPriorKnown   = 1.0
+	readinessPriorUnknown = 3.0
+)
+
+type readinessReport struct {
+	Pass          float64
+	Low           float64
+	High          float64
+	ExpectedScore float64
+	Highlights    int
+	Unseen        int
+	Suggestions   []sliceutils.Pair[*Highlight, float64]
+}
+
+// knowledgePosterior turns a score into a Beta distribution over the chance
+// of knowing the highlight, treating Count as the number of observations.
+func knowledgePosterior(s Score) (float64, float64) {
+	avg := min(1, max(0, s.WeightedAverage()))
+	return readinessPriorKnown + avg*float64(s.Count), readinessPriorUnknown + (1-avg)*float64(s.Count)
+}
+
+func answerProbability(knowledge float64) float64 {
+	return examGuessRate + (1-examGuessRate)*knowledge
+}
+
+// passProbability is the chance of at least examPassMark correct answers when
+// every question is drawn uniformly from the deck and answered correctly with
+// the deck's mean answer probability.
+func passProbability(p float64) float64 {
+	var result float64
+	for k := examPassMark; k <= examQuestions; k++ {
+		result = result + binomial(examQuestions, k)*math.Pow(p, float64(k))*math.Pow(1-p, float64(examQuestions-k))
+	}
+	return result
+}
+
+func binomial(n, k int) float64 {
+	result := 1.0
+	for i := 1; i <= k; i++ {
+		result = result * float64(n-k+i) / float64(i)
+	}
+	return result
+}
+
+func estimateReadiness(highlights HighlightDatabase, runs int) readinessReport {
+	n := len(highlights.Highlights)
+	report := readinessReport{Highlights: n}
+	if n == 0 {
+		return report
+	}
+
+	alphas := make([]float64, n)
+	betas := make([]float64, n)
+	var expected float64
+	for i, h := range highlights.Highlights {
+		alphas[i], betas[i] = knowledgePosterior(h.Score)
+		expected = expected + answerProbability(alphas[i]/(alphas[i]+betas[i]))
+		if h.Score.Count == 0 {
+			report.Unseen = report.Unseen + 1
+		}
+	}
+	expected = expected / float64(n)
+
+	passes := make([]float64, runs)
+	for run := 0; run < runs; run++ {
+		var mean float64
+		for i := 0; i < n; i++ {
+			mean = mean + answerProbability(sampleBeta(highlights.Rand, alphas[i], betas[i]))
+		}
+		passes[run] = passProbability(mean / float64(n))
+	}
+	slices.Sort(passes)
+
+	var sum float64
+	for _, p := range passes {
+		sum = sum + p
+	}
+	report.Pass = sum / float64(max(1, runs))
+	if runs > 0 {
+		report.Low = passes[int(float64(runs-1)*0.025)]
+		report.High = passes[int(float64(runs-1)*0.975)]
+	}
+	report.ExpectedScore = expected * examQuestions
+
+	base := passProbability(expected)
+	today := time.Now().Format(dayFormat)
+	for i := range highlights.Highlights {
+		if !highlights.Highlights[i].Score.Available(today) {
+			continue
+		}
+		gain := passProbability(expected+(1-answerProbability(alphas[i]/(alphas[i]+betas[i])))/float64(n)) - base
+		report.Suggestions = append(report.Suggestions, sliceutils.Pair[*Highlight, float64]{
+			Key:   &highlights.Highlights[i],
+			Value: gain,
+		})
+	}
+	report.Suggestions = sliceutils.SortFunc(report.Suggestions, func(a, b sliceutils.Pair[*Highlight, float64]) int {
+		if c := CompareFloat64(b.Value, a.Value); c != 0 {
+			return c
+		}
+		return a.Key.Score.Count - b.Key.Score.Count
+	})
+	report.Suggestions = report.Suggestions[:min(readinessSuggestions, len(report.Suggestions))]
+
+	return report
+}
+
+func printReadiness(report readinessReport) {
+	fmt.Fprintf(stdout, "\n")
+	fmt.Fprintf(stdout, "  Exam readiness (%d questions, pass mark %d)\n", examQuestions, examPassMark)
+	fmt.Fprintf(stdout, "  Chance of passing today: %s%.0f%%%s (95%% interval %.0f%% - %.0f%%)\n", colorGreen, report.Pass*100, colorNone, report.Low*100, report.High*100)
+	fmt.Fprintf(stdout, "  Expected score:          %.1f/%d\n", report.ExpectedScore, examQuestions)
+	fmt.Fprintf(stdout, "  Deck:                    %d highlights, %d never studied\n", report.Highlights, report.Unseen)
+	fmt.Fprintf(stdout, "\n")
+
+	if len(report.Suggestions) == 0 {
+		return
+	}
+	fmt.Fprintf(stdout, "  Study these first:\n")
+	for j, s := range report.Suggestions {
+		txt := strings.Join(strings.Fields(s.Key.Content), " ")
+		if rs := []rune(txt); len(rs) > alignmentWidth {
+			txt = string(rs[:alignmentWidth]) + "..."
+		}
+		fmt.Fprintf(stdout, "  %2d. +%.2f%%  %s\n", (j + 1), s.Value*100, txt)
+	}
+	fmt.Fprintf(stdout, "\n")
+}
+
+func cmdReadiness(args []string) error {
+	fs := flag.NewFlagSet("readiness", flag.ContinueOnError)
+	runs := fs.Int("runs", readinessRuns, "number of Monte Carlo runs over the deck")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	highlights, err := loadHighlights()
+	if err != nil {
+		return err
+	}
+	printReadiness(estimateReadiness(highlights, *runs))
+	return nil
+}
+
+func sampleBeta(r *rand.Rand, alpha, beta float64) float64 {
+	x := sampleGamma(r, alpha)
+	y := sampleGamma(r, beta)
+	return x / (x + y)
+}
+
+// sampleGamma uses Marsaglia and Tsang's method, boosting shapes below one.
+func sampleGamma(r *rand.Rand, shape float64) float64 {
+	if shape < 1 {
+		return sampleGamma(r, shape+1) * math.Pow(r.Float64(), 1/shape)
+	}
+
+	d := shape - 1.0/3
+	c := 1 / math.Sqrt(9*d)
+	for {
+		x := r.NormFloat64()
+		v := 1 + c*x
+		if v <= 0 {
+			continue
+		}
+		v = v * v * v
+		u := r.Float64()
+		if math.Log(u) < 0.5*x*x+d-d*v+d*math.Log(v) {
+			return d * v
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/arcana261/lifeinuk/sliceutils"
+)
+
+// Version 1 files have no header and positional "ID sum count [lapses
+// flags]" lines. From version 2 on every field after the ID is key=value and
+// fields this build does not know are written back untouched. Files of a
+// newer version are read the same way and keep their version when saved.
+const (
+	scoresHeader  = "# lifeinuk scores v%d"
+	scoresVersion = 2
+)
+
+func scoresFileVersion(line string) (int, bool) {
+	var version int
+	n, err := fmt.Sscanf(line, scoresHeader, &version)
+	return version, n == 1 && err == nil
+}
+
+func formatScore(id string, s Score) string {
+	fields := []string{
+		id,
+		fmt.Sprintf("sum=%f", s.Sum),
+		fmt.Sprintf("count=%d", s.Count),
+	}
+	if s.Lapses > 0 {
+		fields = append(fields, fmt.Sprintf("lapses=%d", s.Lapses))
+	}
+	if len(s.Flags) > 0 {
+		fields = append(fields, fmt.Sprintf("flags=%s", strings.Join(s.Flags, ",")))
+	}
+	if s.Reviewed != "" {
+		fields = append(fields, fmt.Sprintf("reviewed=%s", s.Reviewed))
+	}
+	fields = append(fields, s.Extra...)
+	return strings.Join(fields, " ") + "\n"
+}
+
+// readScores also returns the version in the header of the file, so that
+// saving it again does not downgrade a file written by a newer build.
+func readScores(fname string) (map[string]Score, int, error) {
+	if !fileExists(fname) {
+		return nil, scoresVersion, nil
+	}
+
+	b, err := os.ReadFile(fname)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	today := time.Now().Format(dayFormat)
+	version := 1
+	result := make(map[string]Score)
+	var errs []error
+	for index, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimSpace(line)
+		if v, ok := scoresFileVersion(line); ok && index == 0 {
+			if v > scoresVersion {
+				fmt.Fprintf(os.Stderr, "%s is a version %d scores file, fields this build does not know are kept as they are\n", fname, v)
+			}
+			version = v
+			continue
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		parse := parseScore
+		if version == 1 {
+			parse = parseLegacyScore
+		}
+		id, score, reason := parse(strings.Fields(line))
+		if reason != "" {
+			errs = append(errs, &ScoreLineError{Path: fname, Line: index + 1, Text: line, Reason: reason})
+			continue
+		}
+		result[id] = normalizeScore(score, today)
+	}
+	return result, version, errors.Join(errs...)
+}
+
+func normalizeScore(score Score, today string) Score {
+	score.Flags = sliceutils.RemoveFunc(score.Flags, buriedBefore(today))
+	score.Average = score.WeightedAverage()
+	return score
+}
+
+func parseScore(part []string) (string, Score, string) {
+	var score Score
+	seen := make(map[string]bool)
+	for _, field := range part[1:] {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			return "", Score{}, fmt.Sprintf("field %q is not key=value", field)
+		}
+		seen[key] = true
+
+		var err error
+		switch key {
+		case "sum":
+			score.Sum, err = strconv.ParseFloat(value, 64)
+		case "count":
+			score.Count, err = strconv.Atoi(value)
+		case "lapses":
+			score.Lapses, err = strconv.Atoi(value)
+		case "flags":
+			score.Flags = strings.Split(value, ",")
+		case "reviewed":
+			score.Reviewed = value
+		default:
+			score.Extra = append(score.Extra, field)
+		}
+		if err != nil {
+			return "", Score{}, fmt.Sprintf("%s %q is not a number", key, value)
+		}
+	}
+	if !seen["sum"] || !seen["count"] {
+		return "", Score{}, "sum and count are required"
+	}
+	return part[0], score, ""
+}
+
+func parseLegacyScore(part []string) (string, Score, string) {
+	if len(part) != 3 && len(part) != 5 {
+		return "", Score{}, fmt.Sprintf("expected 3 or 5 fields, got %d", len(part))
+	}
+
+	var score Score
+	n, err := fmt.Sscanf(part[1], "%f", &score.Sum)
+	if n != 1 || err != nil {
+		return "", Score{}, fmt.Sprintf("sum %q is not a number", part[1])
+	}
+	n, err = fmt.Sscanf(part[2], "%d", &score.Count)
+	if n != 1 || err != nil {
+		return "", Score{}, fmt.Sprintf("count %q is not an integer", part[2])
+	}
+	if len(part) == 5 {
+		n, err = fmt.Sscanf(part[3], "%d", &score.Lapses)
+		if n != 1 || err != nil {
+			return "", Score{}, fmt.Sprintf("lapses %q is not an integer", part[3])
+		}
+		if part[4] != "-" {
+			score.Flags = strings.Split(part[4], ",")
+		}
+	}
+	return part[0], score, ""
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/arcana261/lifeinuk/sliceutils"
+)
+
+const (
+	scrambleMaxUnits   = 9
+	scrambleMinClauses = 3
+	clauseBreaks       = ".,;:!?–"
+)
+
+type tokenSpan struct {
+	From int
+	To   int
+}
+
+func clauseSpans(h *Highlight) []tokenSpan {
+	content := []rune(h.Content)
+	var result []tokenSpan
+	from := 0
+	for k := 1; k < len(h.Tokens); k++ {
+		if strings.ContainsAny(string(content[h.TokenEnds[k-1]:h.TokenStarts[k]]), clauseBreaks) {
+			result = append(result, tokenSpan{From: from, To: k})
+			from = k
+		}
+	}
+	if len(h.Tokens) > 0 {
+		result = append(result, tokenSpan{From: from, To: len(h.Tokens)})
+	}
+	return result
+}
+
+func evenSpans(tokenCount int, count int) []tokenSpan {
+	size := max(1, (tokenCount+count-1)/count)
+	var result []tokenSpan
+	for from := 0; from < tokenCount; from = from + size {
+		result = append(result, tokenSpan{From: from, To: min(tokenCount, from+size)})
+	}
+	return result
+}
+
+func scrambleUnits(h *Highlight) []tokenSpan {
+	if len(h.Tokens) <= scrambleMaxUnits {
+		return sliceutils.MapFunc(sliceutils.Range(0, len(h.Tokens)), func(k int) tokenSpan {
+			return tokenSpan{From: k, To: k + 1}
+		})
+	}
+
+	units := clauseSpans(h)
+	if len(units) < scrambleMinClauses {
+		units = evenSpans(len(h.Tokens), scrambleMaxUnits)
+	}
+	for len(units) > scrambleMaxUnits {
+		best := 0
+		for k := 1; k+1 < len(units); k++ {
+			if units[k+1].To-units[k].From < units[best+1].To-units[best].From {
+				best = k
+			}
+		}
+		units[best].To = units[best+1].To
+		units = sliceutils.RemoveAt(units, best+1)
+	}
+	return units
+}
+
+func spanText(h *Highlight, span tokenSpan) string {
+	content := []rune(h.Content)
+	return string(content[h.TokenStarts[span.From]:h.TokenEnds[span.To-1]])
+}
+
+func spanKey(highlights HighlightDatabase, h *Highlight, span tokenSpan) string {
+	return strings.Join(sliceutils.MapFunc(h.Tokens[span.From:span.To], func(id int) string {
+		return highlights.TokenMap[id].Content
+	}), " ")
+}
+
+func scrambleCard(highlights HighlightDatabase) error {
+	h := highlights.PickHighlight()
+	if h == nil {
+		return nil
+	}
+	if err := showLeech(h); err != nil {
+		return err
+	}
+	hContent := []rune(h.Content)
+
+	units := scrambleUnits(h)
+	if len(units) < 2 {
+		printContent(h.Content)
+		return nil
+	}
+
+	remaining := sliceutils.Range(0, len(units))
+	sliceutils.Permutate(highlights.Rand, remaining)
+
+	correctAnswers := 0
+	wrongAnswers := 0
+	misplaced := make(map[int]bool)
+
+	for next := 0; next < len(units)-1; next++ {
+		expected := spanKey(highlights, h, units[next])
+
+		for {
+			prefix := strings.TrimSuffix(string(hContent[:h.TokenStarts[units[next].From]]), " ")
+			printContent(fmt.Sprintf("> %s %s____?%s", prefix, colorYellow, colorNone))
+
+			for j := 0; j < len(remaining); j++ {
+				txt := strings.Join(strings.Fields(spanText(h, units[remaining[j]])), " ")
+				fmt.Fprintf(stdout, "  %d. %s\n", (j + 1), txt)
+			}
+			fmt.Fprintf(stdout, "  Q. Quit\n")
+			fmt.Fprintf(stdout, "\n")
+
+			key, err := readOne()
+			if err != nil {
+				return err
+			}
+			cmd := strings.ToLower(key)
+			if cmd == "q" {
+				return nil
+			}
+			choice, err := strconv.Atoi(cmd)
+			if err != nil || choice < 1 || choice > len(remaining) {
+				continue
+			}
+
+			selected := remaining[choice-1]
+			if spanKey(highlights, h, units[selected]) == expected {
+				fmt.Fprintf(stdout, "%sCORRECT!%s\n", colorGreen, colorNone)
+				correctAnswers = correctAnswers + 1
+				remaining = sliceutils.RemoveAt(remaining, choice-1)
+				break
+			}
+
+			fmt.Fprintf(stdout, "%sWRONG: %s%s\n", colorRed, spanText(h, units[selected]), colorNone)
+			wrongAnswers = wrongAnswers + 1
+			misplaced[next] = true
+		}
+	}
+
+	h.Record(correctAnswers, wrongAnswers)
+
+	var lineToPrint bytes.Buffer
+	lineToPrint.WriteString(string(hContent[:h.TokenStarts[units[0].From]]))
+	for k, unit := range units {
+		if misplaced[k] {
+			lineToPrint.WriteString(colorRed)
+		} else {
+			lineToPrint.WriteString(colorGreen)
+		}
+		lineToPrint.WriteString(spanText(h, unit))
+		lineToPrint.WriteString(colorNone)
+		if k+1 < len(units) {
+			lineToPrint.WriteString(string(hContent[h.TokenEnds[unit.To-1]:h.TokenStarts[units[k+1].From]]))
+		} else {
+			lineToPrint.WriteString(string(hContent[h.TokenEnds[unit.To-1]:]))
+		}
+	}
+	printContent(lineToPrint.String())
+	fmt.Fprintf(stdout, "%d of %d parts misplaced\n", len(misplaced), len(units))
+
+	return saveScores(highlights)
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+const (
+	scriptExpect = "expect "
+	scriptSend   = "send "
+	scriptTail   = 12
+)
+
+var colorCodes = regexp.MustCompile("\033\\[[0-9;]*m")
+
+type scriptStep struct {
+	Line   int
+	Expect string
+	Send   string
+}
+
+// scriptDriver stands in for the terminal. Every send step is only typed
+// once the expect steps before it have shown up in the output, and the
+// session sees the end of input when the script runs out or a step fails.
+type scriptDriver struct {
+	Name    string
+	Steps   []scriptStep
+	Echo    io.Writer
+	output  bytes.Buffer
+	checked int
+	pending []byte
+	err     error
+}
+
+func readScript(fname string) ([]scriptStep, error) {
+	f, err := os.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var steps []scriptStep
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		switch {
+		case text == "" || strings.HasPrefix(text, "#"):
+		case strings.HasPrefix(text, scriptExpect):
+			steps = append(steps, scriptStep{Line: line, Expect: strings.TrimPrefix(text, scriptExpect)})
+		case strings.HasPrefix(text, scriptSend):
+			steps = append(steps, scriptStep{Line: line, Send: strings.TrimPrefix(text, scriptSend) + "\n"})
+		default:
+			return nil, fmt.Errorf("%s:%d: expected \"expect\" or \"send\", got %q", fname, line, text)
+		}
+	}
+	return steps, scanner.Err()
+}
+
+func (d *scriptDriver) Write(p []byte) (int, error) {
+	d.output.Write(p)
+	if d.Echo != nil {
+		d.Echo.Write(p)
+	}
+	return len(p), nil
+}
+
+func (d *scriptDriver) Read(p []byte) (int, error) {
+	for len(d.pending) == 0 {
+		if d.err != nil || !d.sending() {
+			return 0, io.EOF
+		}
+		step := d.Steps[0]
+		d.Steps = d.Steps[1:]
+		if step.Send != "" {
+			d.pending = []byte(step.Send)
+		} else if !d.expect(step) {
+			return 0, io.EOF
+		}
+	}
+	n := copy(p, d.pending)
+	d.pending = d.pending[n:]
+	return n, nil
+}
+
+// sending reports whether any keypresses are left, expectations after the
+// last one are checked by Finish once the session has wound down.
+func (d *scriptDriver) sending() bool {
+	for _, step := range d.Steps {
+		if step.Send != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *scriptDriver) expect(step scriptStep) bool {
+	plain := colorCodes.ReplaceAllString(d.output.String(), "")
+	at := strings.Index(plain[min(d.checked, len(plain)):], step.Expect)
+	if at >= 0 {
+		d.checked = d.checked + at + len(step.Expect)
+		return true
+	}
+
+	lines := strings.Split(strings.TrimRight(plain[min(d.checked, len(plain)):], "\n"), "\n")
+	lines = lines[max(0, len(lines)-scriptTail):]
+	d.err = fmt.Errorf("%s:%d: %q did not appear, the output ended with:\n%s", d.Name, step.Line, step.Expect, strings.Join(lines, "\n"))
+	return false
+}
+
+// Finish checks the expectations after the last keypress against what the
+// session printed on its way out.
+func (d *scriptDriver) Finish() error {
+	for d.err == nil && len(d.Steps) > 0 {
+		step := d.Steps[0]
+		d.Steps = d.Steps[1:]
+		if step.Send != "" {
+			d.err = fmt.Errorf("%s:%d: the session ended before %q was sent", d.Name, step.Line, strings.TrimSpace(step.Send))
+			break
+		}
+		d.expect(step)
+	}
+	return d.err
+}
+
+func cmdScript(args []string) error {
+	fs := flag.NewFlagSet("script", flag.ContinueOnError)
+	verbose := fs.Bool("v", false, "print the session while it is replayed")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("usage: script [-v] FILE...")
+	}
+
+	for _, fname := range fs.Args() {
+		steps, err := readScript(fname)
+		if err != nil {
+			return err
+		}
+		driver := &scriptDriver{Name: fname, Steps: steps}
+		if *verbose {
+			driver.Echo = os.Stdout
+		}
+
+		stdin, stdout = driver, driver
+		err = study()
+		stdin, stdout = os.Stdin, os.Stdout
+		if err != nil {
+			return err
+		}
+		if err := driver.Finish(); err != nil {
+			fmt.Fprintf(stdout, "%sFAIL%s %s\n", colorRed, colorNone, fname)
+			return err
+		}
+		fmt.Fprintf(stdout, "%sok%s   %s\n", colorGreen, colorNone, fname)
+	}
+	return nil
+}
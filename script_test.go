@@ -0,0 +1,90 @@
+package main
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// copyDir copies the files under src into dst, keeping their layout.
+func copyDir(t *testing.T, src string, dst string) {
+	err := filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return os.MkdirAll(filepath.Join(dst, rel), 0755)
+		}
+		return copyFile(path, filepath.Join(dst, rel))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestSessions replays every script in testdata/sessions, in order and in
+// one copy of the directory, the way "script" runs them from the command
+// line.
+func TestSessions(t *testing.T) {
+	src, err := filepath.Abs(filepath.Join("testdata", "sessions"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	scripts, err := filepath.Glob(filepath.Join(src, "*.script"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scripts) == 0 {
+		t.Fatal("no scripts in testdata/sessions")
+	}
+
+	dir := t.TempDir()
+	copyDir(t, src, dir)
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.Chdir(wd)
+	})
+
+	oldSeed := *seed
+	*seed = 1
+	store, err = openStore(storeText, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		*seed = oldSeed
+		store.Close()
+		store = nil
+		stdin, stdout = os.Stdin, os.Stdout
+	})
+
+	for _, script := range scripts {
+		t.Run(filepath.Base(script), func(t *testing.T) {
+			steps, err := readScript(script)
+			if err != nil {
+				t.Fatal(err)
+			}
+			driver := &scriptDriver{Name: filepath.Base(script), Steps: steps}
+			stdin, stdout = driver, driver
+			err = study()
+			stdin, stdout = os.Stdin, os.Stdout
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := driver.Finish(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	dailyFile = "daily.txt"
+	dayFormat = "2006-01-02"
+)
+
+type sessionGoal struct {
+	New     int
+	Reviews int
+	Minutes int
+}
+
+func (g sessionGoal) IsSet() bool {
+	return g.New > 0 || g.Reviews > 0 || g.Minutes > 0
+}
+
+type dailyProgress struct {
+	Day      string
+	New      int
+	Reviews  int
+	Seconds  int
+	Streak   int
+	Best     int
+	LastGoal string
+}
+
+type session struct {
+	Goal    sessionGoal
+	Daily   dailyProgress
+	Seed    int64
+	Started time.Time
+	Ratios  []float64
+	New     int
+	Reviews int
+	Before  map[string]Score
+	After   map[string]Score
+}
+
+func readDaily(fname string, now time.Time) (dailyProgress, error) {
+	today := now.Format(dayFormat)
+	yesterday := now.AddDate(0, 0, -1).Format(dayFormat)
+
+	var d dailyProgress
+	if fileExists(fname) {
+		b, err := os.ReadFile(fname)
+		if err != nil {
+			return dailyProgress{}, err
+		}
+		n, err := fmt.Sscanf(strings.TrimSpace(string(b)), "%s %d %d %d %d %d %s", &d.Day, &d.New, &d.Reviews, &d.Seconds, &d.Streak, &d.Best, &d.LastGoal)
+		if n != 7 || err != nil {
+			d = dailyProgress{}
+		}
+	}
+
+	if d.Day != today {
+		d.Day = today
+		d.New = 0
+		d.Reviews = 0
+		d.Seconds = 0
+	}
+	if d.LastGoal != today && d.LastGoal != yesterday {
+		d.Streak = 0
+	}
+	if d.LastGoal == "" {
+		d.LastGoal = "-"
+	}
+	return d, nil
+}
+
+func writeDaily(fname string, d dailyProgress) error {
+	line := fmt.Sprintf("%s %d %d %d %d %d %s\n", d.Day, d.New, d.Reviews, d.Seconds, d.Streak, d.Best, d.LastGoal)
+	err := os.WriteFile(fname, []byte(line), 0644)
+	if err != nil {
+		return &WriteError{Path: fname, Err: err}
+	}
+	return nil
+}
+
+func newSession(goal sessionGoal, daily dailyProgress, seed int64, now time.Time) *session {
+	return &session{
+		Goal:    goal,
+		Daily:   daily,
+		Seed:    seed,
+		Started: now,
+		Before:  make(map[string]Score),
+		After:   make(map[string]Score),
+	}
+}
+
+func snapshotScores(highlights HighlightDatabase) map[string]Score {
+	result := make(map[string]Score)
+	for _, h := range highlights.Highlights {
+		result[h.ID] = h.Score
+	}
+	return result
+}
+
+// Observe compares the scores taken before a game with the database after it
+// and accounts for every highlight whose Count went up.
+func (s *session) Observe(before map[string]Score, highlights HighlightDatabase) {
+	for _, h := range highlights.Highlights {
+		old, ok := before[h.ID]
+		if !ok || h.Score.Count <= old.Count {
+			continue
+		}
+
+		s.Ratios = append(s.Ratios, (h.Score.Sum-old.Sum)/float64(h.Score.Count))
+		if old.Count == 0 {
+			s.New = s.New + 1
+			s.Daily.New = s.Daily.New + 1
+		} else {
+			s.Reviews = s.Reviews + 1
+			s.Daily.Reviews = s.Daily.Reviews + 1
+		}
+		if _, ok := s.Before[h.ID]; !ok {
+			s.Before[h.ID] = old
+		}
+		s.After[h.ID] = h.Score
+	}
+}
+
+// Eligible stops offering unseen highlights once the daily new goal is
+// reached, so the rest of the session is spent on reviews.
+func (s *session) Eligible(h Highlight) bool {
+	return s.Goal.New == 0 || s.Daily.New < s.Goal.New || h.Score.Count > 0
+}
+
+func (s *session) dailySeconds(now time.Time) int {
+	return s.Daily.Seconds + int(now.Sub(s.Started).Seconds())
+}
+
+func (s *session) GoalMet(now time.Time) bool {
+	if !s.Goal.IsSet() {
+		return false
+	}
+	return s.Daily.New >= s.Goal.New &&
+		s.Daily.Reviews >= s.Goal.Reviews &&
+		s.dailySeconds(now) >= s.Goal.Minutes*60
+}
+
+func (s *session) Finish(now time.Time) dailyProgress {
+	d := s.Daily
+	d.Seconds = s.dailySeconds(now)
+
+	yesterday := now.AddDate(0, 0, -1).Format(dayFormat)
+	earned := s.GoalMet(now) || (!s.Goal.IsSet() && len(s.Ratios) > 0)
+	if earned && d.LastGoal != d.Day {
+		if d.LastGoal == yesterday {
+			d.Streak = d.Streak + 1
+		} else {
+			d.Streak = 1
+		}
+		d.LastGoal = d.Day
+		d.Best = max(d.Best, d.Streak)
+	}
+	return d
+}
+
+func (s *session) PrintSummary(now time.Time, d dailyProgress) {
+	var accuracy float64
+	for _, r := range s.Ratios {
+		accuracy = accuracy + r
+	}
+	accuracy = accuracy / float64(max(1, len(s.Ratios)))
+
+	improved := 0
+	regressed := 0
+	for id, after := range s.After {
+		c := CompareFloat64(after.WeightedAverage(), s.Before[id].WeightedAverage())
+		if c > 0 {
+			improved = improved + 1
+		} else if c < 0 {
+			regressed = regressed + 1
+		}
+	}
+
+	fmt.Fprintf(stdout, "\n")
+	fmt.Fprintf(stdout, "  Session summary\n")
+	fmt.Fprintf(stdout, "  Time spent:  %s\n", now.Sub(s.Started).Round(time.Second))
+	fmt.Fprintf(stdout, "  Studied:     %d (%d new, %d reviews)\n", len(s.Ratios), s.New, s.Reviews)
+	fmt.Fprintf(stdout, "  Accuracy:    %.0f%%\n", accuracy*100)
+	fmt.Fprintf(stdout, "  Improved:    %s%d%s\n", colorGreen, improved, colorNone)
+	fmt.Fprintf(stdout, "  Regressed:   %s%d%s\n", colorRed, regressed, colorNone)
+	if s.Goal.IsSet() {
+		var parts []string
+		if s.Goal.New > 0 {
+			parts = append(parts, fmt.Sprintf("%d/%d new", d.New, s.Goal.New))
+		}
+		if s.Goal.Reviews > 0 {
+			parts = append(parts, fmt.Sprintf("%d/%d reviews", d.Reviews, s.Goal.Reviews))
+		}
+		if s.Goal.Minutes > 0 {
+			parts = append(parts, fmt.Sprintf("%d/%d minutes", d.Seconds/60, s.Goal.Minutes))
+		}
+		fmt.Fprintf(stdout, "  Daily goal:  %s\n", strings.Join(parts, ", "))
+	}
+	fmt.Fprintf(stdout, "  Streak:      %d days (best %d)\n", d.Streak, d.Best)
+	if *profile != "" {
+		fmt.Fprintf(stdout, "  Profile:     %s\n", *profile)
+	}
+	fmt.Fprintf(stdout, "  Seed:        %d (replay with -seed %d)\n", s.Seed, s.Seed)
+	fmt.Fprintf(stdout, "\n")
+}
@@ -296,9 +296,9 @@ func ToMultiMapFunc[E ~[]T, T any, K comparable, V any](s E, fn func(T) (K, V))
 	})
 }
 
-func Permutate[E ~[]T, T any](s E) {
+func Permutate[E ~[]T, T any](r *rand.Rand, s E) {
 	for j := 0; j < len(s); j++ {
-		k := j + rand.Intn(len(s)-j)
+		k := j + r.Intn(len(s)-j)
 		if j != k {
 			temp := s[j]
 			s[j] = s[k]
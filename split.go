@@ -0,0 +1,235 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/arcana261/lifeinuk/sliceutils"
+)
+
+const (
+	sentenceBreaks = ".!?"
+)
+
+var splitTokens = flag.Int("split", 0, "split highlights longer than this many tokens into sentence cards, 0 keeps them whole")
+
+// subID names a sentence group after the sentence it starts at, and after
+// the clause within it when a long sentence was cut, so the ID stays the same
+// whatever -split groups the sentences into.
+func subID(parent string, sentence int, clause int) string {
+	if clause == 0 {
+		return fmt.Sprintf("%s#%d", parent, sentence)
+	}
+	return fmt.Sprintf("%s#%d.%d", parent, sentence, clause)
+}
+
+// subIndex parses the sentence and clause numbers back out of a sub-card
+// ID, nil for a whole highlight.
+func subIndex(id string) []int {
+	_, sub, ok := strings.Cut(id, "#")
+	if !ok {
+		return nil
+	}
+	return sliceutils.MapFunc(strings.Split(sub, "."), func(x string) int {
+		n, _ := strconv.Atoi(x)
+		return n
+	})
+}
+
+// spanSentence returns the sentence a span starts in and the clause within
+// that sentence, 0 when the span starts at the beginning of the sentence.
+func spanSentence(h *Highlight, span tokenSpan) (int, int) {
+	for sentence, s := range breakSpans(h, tokenSpan{From: 0, To: len(h.Tokens)}, sentenceBreaks) {
+		if span.From >= s.To {
+			continue
+		}
+		for clause, c := range breakSpans(h, s, clauseBreaks) {
+			if span.From < c.To {
+				return sentence, clause
+			}
+		}
+	}
+	return 0, 0
+}
+
+func breakSpans(h *Highlight, span tokenSpan, breaks string) []tokenSpan {
+	content := []rune(h.Content)
+	var result []tokenSpan
+	from := span.From
+	for k := span.From + 1; k < span.To; k++ {
+		if strings.ContainsAny(string(content[h.TokenEnds[k-1]:h.TokenStarts[k]]), breaks) {
+			result = append(result, tokenSpan{From: from, To: k})
+			from = k
+		}
+	}
+	return append(result, tokenSpan{From: from, To: span.To})
+}
+
+// sentenceSpans cuts a highlight into sentences, cuts sentences that are
+// still too long at clause breaks and then joins neighbours back together
+// as long as they fit in maxTokens.
+func sentenceSpans(h *Highlight, maxTokens int) []tokenSpan {
+	var pieces []tokenSpan
+	for _, sentence := range breakSpans(h, tokenSpan{From: 0, To: len(h.Tokens)}, sentenceBreaks) {
+		if sentence.To-sentence.From > maxTokens {
+			pieces = append(pieces, breakSpans(h, sentence, clauseBreaks)...)
+		} else {
+			pieces = append(pieces, sentence)
+		}
+	}
+
+	var result []tokenSpan
+	for _, piece := range pieces {
+		if len(result) > 0 && piece.To-result[len(result)-1].From <= maxTokens {
+			result[len(result)-1].To = piece.To
+			continue
+		}
+		result = append(result, piece)
+	}
+	return result
+}
+
+func subHighlight(h *Highlight, span tokenSpan) Highlight {
+	content := []rune(h.Content)
+	start := h.TokenStarts[span.From]
+	end := len(content)
+	if span.To < len(h.Tokens) {
+		end = h.TokenStarts[span.To]
+	}
+	text := strings.TrimRight(string(content[start:end]), " \t\r\n")
+	sentence, clause := spanSentence(h, span)
+
+	return Highlight{
+		ID:      subID(h.ID, sentence, clause),
+		Content: text,
+		Source:  h.Source,
+		Index:   h.Index,
+		Parent:  h.ID,
+		Tokens:  sliceutils.Clone(h.Tokens[span.From:span.To]),
+		TokenStarts: sliceutils.MapFunc(h.TokenStarts[span.From:span.To], func(x int) int {
+			return x - start
+		}),
+		TokenEnds: sliceutils.MapFunc(h.TokenEnds[span.From:span.To], func(x int) int {
+			return x - start
+		}),
+	}
+}
+
+// splitHighlights replaces every highlight longer than maxTokens with one
+// card per sentence group. Sub-cards keep their own scores, the score of
+// the whole paragraph is kept aside so it survives running without -split.
+func splitHighlights(db HighlightDatabase, maxTokens int) HighlightDatabase {
+	if maxTokens <= 0 {
+		return db
+	}
+
+	db.Sources = make(map[string]*Highlight)
+	var result []Highlight
+	for k := range db.Highlights {
+		h := db.Highlights[k]
+		if len(h.Tokens) <= maxTokens || h.Question != "" {
+			result = append(result, h)
+			continue
+		}
+		spans := sentenceSpans(&h, maxTokens)
+		if len(spans) < 2 {
+			result = append(result, h)
+			continue
+		}
+
+		source := h
+		db.Sources[h.ID] = &source
+		if !h.Score.IsEmpty() {
+			db.UnmatchedScores[h.ID] = h.Score
+		}
+		for _, span := range spans {
+			sub := subHighlight(&source, span)
+			if score, ok := db.UnmatchedScores[sub.ID]; ok {
+				sub.Score = score
+				delete(db.UnmatchedScores, sub.ID)
+			}
+			result = append(result, sub)
+		}
+	}
+
+	assignProbabilities(result)
+	db.Highlights = result
+	return db
+}
+
+// setSubContent edits one sub-card and splices the new text into its source
+// paragraph. The paragraph ID changes with its content, so the sub-cards of
+// the paragraph are renamed to keep their scores.
+func (db HighlightDatabase) setSubContent(h *Highlight, content string, maxTokens int) {
+	source := db.Sources[h.Parent]
+	oldParent := h.Parent
+	spans := sentenceSpans(source, maxTokens)
+	index := len(spans) - 1
+	// starts keeps the first token of every sub-card, to find the sentence
+	// each one starts at once the paragraph changed.
+	starts := make(map[string]int)
+	for k, span := range spans {
+		id := subHighlight(source, span).ID
+		starts[id] = span.From
+		if id == h.ID {
+			index = k
+		}
+	}
+	edited := spans[index]
+	oldTokens := len(source.Tokens)
+	sourceContent := []rune(source.Content)
+	start := source.TokenStarts[spans[index].From]
+	end := len(sourceContent)
+	if spans[index].To < len(source.Tokens) {
+		end = source.TokenStarts[spans[index].To]
+	}
+	old := string(sourceContent[start:end])
+	trailing := old[len(strings.TrimRight(old, " \t\r\n")):]
+	newSource := string(sourceContent[:start]) + content + trailing + string(sourceContent[end:])
+
+	affected := sliceutils.Clone(h.Tokens)
+	db.SetContent(source, fixAlignment(newSource, alignmentWidth), source.Question)
+	// The text before the edit is unchanged, so the edited card is the group
+	// that starts where the old one did.
+	spans = sentenceSpans(source, maxTokens)
+	index = 0
+	for k, span := range spans {
+		if span.From <= edited.From {
+			index = k
+		}
+	}
+	sub := subHighlight(source, spans[index])
+	sub.Score = h.Score
+	*h = sub
+	db.rebuildNextTokens(sliceutils.UniqueSorted(sliceutils.Sort(append(affected, h.Tokens...))))
+
+	delete(db.Sources, oldParent)
+	db.Sources[source.ID] = source
+	if score, ok := db.UnmatchedScores[oldParent]; ok {
+		delete(db.UnmatchedScores, oldParent)
+		db.UnmatchedScores[source.ID] = score
+	}
+	// The other sub-cards keep their text but may start at another sentence
+	// now, their tokens moved by as many as the edit added.
+	delta := len(source.Tokens) - oldTokens
+	for k := range db.Highlights {
+		other := &db.Highlights[k]
+		if other == h || other.Parent != oldParent {
+			continue
+		}
+		from, ok := starts[other.ID]
+		if !ok {
+			other.ID = source.ID + strings.TrimPrefix(other.ID, oldParent)
+			other.Parent = source.ID
+			continue
+		}
+		if from >= edited.To {
+			from = from + delta
+		}
+		sentence, clause := spanSentence(source, tokenSpan{From: from})
+		other.ID = subID(source.ID, sentence, clause)
+		other.Parent = source.ID
+	}
+}
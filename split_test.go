@@ -0,0 +1,92 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// splitDeck reads a deck of one paragraph and splits it into sub-cards of at
+// most maxTokens tokens.
+func splitDeck(t *testing.T, content string, maxTokens int) HighlightDatabase {
+	t.Helper()
+	dir := t.TempDir()
+	s := &textStore{Deck: filepath.Join(dir, "highlights.txt"), Scores: filepath.Join(dir, scoresFile)}
+	if err := os.WriteFile(s.Deck, []byte(content+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	db, err := ReadHighlights(s, filepath.Join(dir, "phrases.txt"), filepath.Join(dir, "aliases.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return splitHighlights(db, maxTokens)
+}
+
+func subSuffixes(db HighlightDatabase) []string {
+	var result []string
+	for _, h := range db.Highlights {
+		result = append(result, strings.TrimPrefix(h.ID, h.Parent))
+	}
+	return slices.Sorted(slices.Values(result))
+}
+
+// TestSetSubContentSiblings edits the first sub-card so the paragraph gains
+// or loses a sentence; the other sub-cards must get the IDs a fresh split of
+// the edited paragraph gives them and keep their scores.
+func TestSetSubContentSiblings(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		edit    string
+		before  []string
+		after   []string
+	}{
+		{
+			name:    "sentence removed",
+			content: "One two. Three four. Five six seven. Eight nine ten.",
+			edit:    "One two three four.",
+			before:  []string{"#0", "#2", "#3"},
+			after:   []string{"#0", "#1", "#2"},
+		},
+		{
+			name:    "sentence added",
+			content: "One two three four. Five six seven. Eight nine ten.",
+			edit:    "One two. Three four.",
+			before:  []string{"#0", "#1", "#2"},
+			after:   []string{"#0", "#2", "#3"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db := splitDeck(t, test.content, 4)
+			if got := subSuffixes(db); !slices.Equal(got, test.before) {
+				t.Fatalf("sub-cards before the edit %v, want %v", got, test.before)
+			}
+			counts := make(map[string]int)
+			for k := range db.Highlights {
+				h := &db.Highlights[k]
+				h.Score.Count = k + 1
+				counts[strings.Join(strings.Fields(h.Content), " ")] = k + 1
+			}
+
+			db.setSubContent(db.FindHighlight(db.Highlights[0].Parent+"#0"), test.edit, 4)
+			if got := subSuffixes(db); !slices.Equal(got, test.after) {
+				t.Errorf("sub-cards after the edit %v, want %v", got, test.after)
+			}
+
+			fresh := splitDeck(t, db.Sources[db.Highlights[0].Parent].Content, 4)
+			scores := db.Scores()
+			for _, h := range fresh.Highlights {
+				content := strings.Join(strings.Fields(h.Content), " ")
+				if content == test.edit {
+					continue
+				}
+				if scores[h.ID].Count != counts[content] {
+					t.Errorf("%q has count %d under %s, want %d", content, scores[h.ID].Count, h.ID, counts[content])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/arcana261/lifeinuk/maputils"
+	"github.com/arcana261/lifeinuk/sliceutils"
+)
+
+const (
+	storeText = "text"
+	storeDB   = "db"
+	dbFile    = "lifeinuk.db"
+)
+
+var storeKind = flag.String("store", storeText, "where the deck and scores are kept: text (data/highlights.txt and scores.txt) or db (a single lifeinuk.db file)")
+
+// Store keeps the deck and the scores. WriteDeck and WriteScores either
+// apply completely or not at all; WriteScores gets every score and is free
+// to write only the ones that changed.
+type Store interface {
+	ReadDeck() (string, error)
+	WriteDeck(deck string) error
+	ReadScores() (map[string]Score, error)
+	WriteScores(scores map[string]Score) error
+	Close() error
+}
+
+var store Store
+
+func openStore(kind string, name string) (Store, error) {
+	if name != "" {
+		if err := checkProfile(name); err != nil {
+			return nil, err
+		}
+	}
+	text := &textStore{Deck: highlightsFile, Scores: profilePath(name, scoresFile)}
+	switch kind {
+	case storeText:
+		return text, nil
+	case storeDB:
+		return openDBStore(dbFile, text, name)
+	default:
+		return nil, fmt.Errorf("unknown store %q, use %s or %s", kind, storeText, storeDB)
+	}
+}
+
+type textStore struct {
+	Deck   string
+	Scores string
+	// version is the header version of the scores file as last read.
+	version int
+}
+
+func (s *textStore) ReadDeck() (string, error) {
+	bs, err := os.ReadFile(s.Deck)
+	if os.IsNotExist(err) {
+		return "", &DeckNotFoundError{Path: s.Deck}
+	}
+	if err != nil {
+		return "", err
+	}
+	return string(bs), nil
+}
+
+func (s *textStore) WriteDeck(deck string) error {
+	if fileExists(s.Deck) {
+		if err := copyFile(s.Deck, filepath.Base(s.Deck)+".bak"); err != nil {
+			return err
+		}
+	}
+	return writeFileAtomic(s.Deck, []byte(deck))
+}
+
+func (s *textStore) ReadScores() (map[string]Score, error) {
+	scores, version, err := readScores(s.Scores)
+	s.version = version
+	return scores, err
+}
+
+func (s *textStore) WriteScores(scores map[string]Score) error {
+	if fileExists(s.Scores) {
+		if err := copyFile(s.Scores, s.Scores+".bak"); err != nil {
+			return err
+		}
+	}
+	return writeFileAtomic(s.Scores, []byte(formatScores(scores, max(s.version, scoresVersion))))
+}
+
+func (s *textStore) Close() error {
+	return nil
+}
+
+func formatScores(scores map[string]Score, version int) string {
+	lines := sliceutils.MapFunc(maputils.ToEntries(scores), func(p sliceutils.Pair[string, Score]) string {
+		return formatScore(p.Key, p.Value)
+	})
+	lines = append([]string{fmt.Sprintf(scoresHeader+"\n", version)}, sliceutils.Sort(lines)...)
+	return strings.Join(lines, "")
+}
+
+// writeFileAtomic writes to a temporary file next to fname and renames it
+// over the original, so an interrupted write never leaves half a file.
+func writeFileAtomic(fname string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(fname), "."+filepath.Base(fname)+"-*")
+	if err != nil {
+		return &WriteError{Path: fname, Err: err}
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return &WriteError{Path: fname, Err: err}
+	}
+	if err := f.Chmod(0644); err != nil {
+		f.Close()
+		return &WriteError{Path: fname, Err: err}
+	}
+	if err := f.Close(); err != nil {
+		return &WriteError{Path: fname, Err: err}
+	}
+	if err := os.Rename(f.Name(), fname); err != nil {
+		return &WriteError{Path: fname, Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,428 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/arcana261/lifeinuk/maputils"
+	"github.com/arcana261/lifeinuk/sliceutils"
+)
+
+const (
+	reviewsFile     = "reviews.log"
+	syncStateFile   = "sync.txt"
+	syncServerLog   = "sync.log"
+	syncServerAddr  = ":8077"
+	syncPath        = "/reviews"
+	syncTimeout     = 30 * time.Second
+	syncMaxBodySize = 64 << 20
+)
+
+// Review is a single graded review. A review is identified by its highlight
+// and its time, so the same review pushed or pulled twice is counted once.
+type Review struct {
+	ID    string
+	Time  time.Time
+	Ratio float64
+}
+
+// pendingReviews are reviews recorded since the last saveScores.
+var pendingReviews []Review
+
+func (r Review) Key() string {
+	return r.ID + " " + r.Time.UTC().Format(time.RFC3339Nano)
+}
+
+func formatReview(r Review) string {
+	return fmt.Sprintf("%s %f\n", r.Key(), r.Ratio)
+}
+
+func parseReviews(name string, data []byte) ([]Review, error) {
+	var result []Review
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		part := strings.Fields(line)
+		if len(part) != 3 {
+			return nil, fmt.Errorf("%s:%d: malformed review %q", name, i+1, line)
+		}
+		at, err := time.Parse(time.RFC3339Nano, part[1])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: malformed review time %q", name, i+1, part[1])
+		}
+		ratio, err := strconv.ParseFloat(part[2], 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: malformed review ratio %q", name, i+1, part[2])
+		}
+		result = append(result, Review{ID: part[0], Time: at, Ratio: ratio})
+	}
+	return result, nil
+}
+
+func readReviews(fname string) ([]Review, error) {
+	data, err := os.ReadFile(fname)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseReviews(fname, data)
+}
+
+func appendReviews(fname string, reviews []Review) error {
+	if len(reviews) == 0 {
+		return nil
+	}
+	f, err := os.OpenFile(fname, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return &WriteError{Path: fname, Err: err}
+	}
+	var buff bytes.Buffer
+	for _, r := range reviews {
+		buff.WriteString(formatReview(r))
+	}
+	_, err = f.Write(buff.Bytes())
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return &WriteError{Path: fname, Err: err}
+	}
+	return nil
+}
+
+func reviewKeys(reviews []Review) map[string]bool {
+	result := make(map[string]bool)
+	for _, r := range reviews {
+		result[r.Key()] = true
+	}
+	return result
+}
+
+// applyReview records a review made on another device. Sum weighs it by the
+// order it arrived in until reorderReviews puts it in its place.
+func applyReview(highlights HighlightDatabase, r Review) {
+	if h := highlights.FindHighlight(r.ID); h != nil {
+		h.Score.RecordRatioAt(r.Ratio, r.Time)
+		return
+	}
+	s := highlights.UnmatchedScores[r.ID]
+	s.RecordRatioAt(r.Ratio, r.Time)
+	s.Average = s.WeightedAverage()
+	highlights.UnmatchedScores[r.ID] = s
+}
+
+func compareReviews(a, b Review) int {
+	if c := a.Time.Compare(b.Time); c != 0 {
+		return c
+	}
+	return strings.Compare(a.ID, b.ID)
+}
+
+// replaySum is what the reviews add to Sum when they are, in this order, the
+// last of count reviews.
+func replaySum(reviews []Review, count int) float64 {
+	sum := 0.0
+	for k, r := range reviews {
+		sum = sum + r.Ratio*float64(count-len(reviews)+k+1)
+	}
+	return sum
+}
+
+// reorderReviews makes the Sum of every card that got fresh reviews the one
+// of its journal sorted by time and ID, so devices that exchanged the same
+// reviews in any order agree. The score already weighs the journal in that
+// order, as earlier syncs left it; the fresh reviews were added after it in
+// the order they arrived. Reviews older than the journal stay where they
+// were.
+func reorderReviews(highlights HighlightDatabase, journal []Review, fresh []Review) {
+	ids := sliceutils.UniqueSorted(sliceutils.Sort(sliceutils.MapFunc(fresh, func(r Review) string {
+		return r.ID
+	})))
+	for _, id := range ids {
+		ofCard := func(r Review) bool {
+			return r.ID == id
+		}
+		known := make(map[string]bool)
+		var applied []Review
+		for _, r := range sliceutils.SortFunc(sliceutils.FilterFunc(journal, ofCard), compareReviews) {
+			if !known[r.Key()] {
+				known[r.Key()] = true
+				applied = append(applied, r)
+			}
+		}
+		applied = append(applied, sliceutils.FilterFunc(fresh, ofCard)...)
+
+		score := highlights.UnmatchedScores[id]
+		h := highlights.FindHighlight(id)
+		if h != nil {
+			score = h.Score
+		}
+		if len(applied) > score.Count {
+			continue
+		}
+		score.Sum = score.Sum - replaySum(applied, score.Count) + replaySum(sliceutils.SortFunc(applied, compareReviews), score.Count)
+		score.Average = score.WeightedAverage()
+		if h != nil {
+			h.Score = score
+		} else {
+			highlights.UnmatchedScores[id] = score
+		}
+	}
+}
+
+// syncServer relays reviews between devices. It keeps every review it was
+// sent once, in arrival order; GET /reviews?since=N returns the ones after
+// the first N and POST /reviews adds new ones.
+type syncServer struct {
+	Log     string
+	mu      sync.Mutex
+	reviews []Review
+	keys    map[string]bool
+}
+
+func newSyncServer(fname string) (*syncServer, error) {
+	reviews, err := readReviews(fname)
+	if err != nil {
+		return nil, err
+	}
+	return &syncServer{Log: fname, reviews: reviews, keys: reviewKeys(reviews)}, nil
+}
+
+func (s *syncServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != syncPath {
+		http.NotFound(w, r)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		s.serveReviews(w, r)
+	case http.MethodPost:
+		s.addReviews(w, r)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *syncServer) serveReviews(w http.ResponseWriter, r *http.Request) {
+	since := 0
+	if v := r.URL.Query().Get("since"); v != "" {
+		var err error
+		if since, err = strconv.Atoi(v); err != nil || since < 0 {
+			http.Error(w, "invalid since", http.StatusBadRequest)
+			return
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	for _, review := range s.reviews[min(since, len(s.reviews)):] {
+		io.WriteString(w, formatReview(review))
+	}
+}
+
+func (s *syncServer) addReviews(w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, syncMaxBodySize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	reviews, err := parseReviews("request", data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var fresh []Review
+	for _, review := range reviews {
+		if !s.keys[review.Key()] {
+			s.keys[review.Key()] = true
+			fresh = append(fresh, review)
+		}
+	}
+	if err := appendReviews(s.Log, fresh); err != nil {
+		for _, review := range fresh {
+			delete(s.keys, review.Key())
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.reviews = append(s.reviews, fresh...)
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintf(w, "%d\n", len(fresh))
+}
+
+// syncState remembers how far the local journal was pushed to, and the
+// server's reviews were pulled from, each server.
+type syncState struct {
+	Pushed int
+	Pulled int
+}
+
+func readSyncState(fname string) (map[string]syncState, error) {
+	result := make(map[string]syncState)
+	data, err := os.ReadFile(fname)
+	if os.IsNotExist(err) {
+		return result, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i, line := range sliceutils.Remove(sliceutils.TrimSpace(strings.Split(string(data), "\n")), "") {
+		var url string
+		var state syncState
+		if _, err := fmt.Sscanf(line, "%s pushed=%d pulled=%d", &url, &state.Pushed, &state.Pulled); err != nil {
+			return nil, fmt.Errorf("%s:%d: malformed sync state %q", fname, i+1, line)
+		}
+		result[url] = state
+	}
+	return result, nil
+}
+
+func writeSyncState(fname string, states map[string]syncState) error {
+	var buff bytes.Buffer
+	for _, url := range sliceutils.Sort(maputils.Keys(states)) {
+		fmt.Fprintf(&buff, "%s pushed=%d pulled=%d\n", url, states[url].Pushed, states[url].Pulled)
+	}
+	return writeFileAtomic(fname, buff.Bytes())
+}
+
+// syncReviews pushes the journal after state.Pushed to the server, then pulls
+// and applies the reviews this device has not seen yet. Pulled reviews are
+// queued for the journal; saving the scores writes them.
+func syncReviews(client *http.Client, url string, journal []Review, state syncState, highlights HighlightDatabase) (syncState, int, error) {
+	url = strings.TrimSuffix(url, "/") + syncPath
+
+	var buff bytes.Buffer
+	for _, r := range journal[min(state.Pushed, len(journal)):] {
+		buff.WriteString(formatReview(r))
+	}
+	if buff.Len() > 0 {
+		resp, err := client.Post(url, "text/plain; charset=utf-8", &buff)
+		if err != nil {
+			return state, 0, err
+		}
+		if err := syncResponse(resp, nil); err != nil {
+			return state, 0, err
+		}
+	}
+
+	resp, err := client.Get(fmt.Sprintf("%s?since=%d", url, state.Pulled))
+	if err != nil {
+		return state, 0, err
+	}
+	var body []byte
+	if err := syncResponse(resp, &body); err != nil {
+		return state, 0, err
+	}
+	pulled, err := parseReviews(url, body)
+	if err != nil {
+		return state, 0, err
+	}
+
+	known := reviewKeys(journal)
+	var fresh []Review
+	for _, r := range pulled {
+		if known[r.Key()] {
+			continue
+		}
+		known[r.Key()] = true
+		applyReview(highlights, r)
+		fresh = append(fresh, r)
+	}
+	reorderReviews(highlights, journal, fresh)
+	pendingReviews = append(pendingReviews, fresh...)
+	return syncState{Pushed: len(journal) + len(fresh), Pulled: state.Pulled + len(pulled)}, len(fresh), nil
+}
+
+func syncResponse(resp *http.Response, body *[]byte) error {
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("sync server: %s: %s", resp.Status, strings.TrimSpace(string(data)))
+	}
+	if body != nil {
+		*body = data
+	}
+	return nil
+}
+
+func cmdSync(args []string) error {
+	fs := flag.NewFlagSet("sync", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: sync URL")
+	}
+	url := fs.Arg(0)
+
+	unlock, err := lockData()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	highlights, err := loadHighlights()
+	if err != nil {
+		return err
+	}
+	journal, err := readReviews(profilePath(*profile, reviewsFile))
+	if err != nil {
+		return err
+	}
+	states, err := readSyncState(profilePath(*profile, syncStateFile))
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{Timeout: syncTimeout}
+	state, fresh, err := syncReviews(client, url, journal, states[url], highlights)
+	if err != nil {
+		return err
+	}
+	if err := saveScores(highlights); err != nil {
+		return err
+	}
+	pushed := len(journal) - min(states[url].Pushed, len(journal))
+	states[url] = state
+	if err := writeSyncState(profilePath(*profile, syncStateFile), states); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "Pushed %d reviews, pulled %d new reviews.\n", pushed, fresh)
+	return nil
+}
+
+func cmdSyncServer(args []string) error {
+	fs := flag.NewFlagSet("sync-server", flag.ContinueOnError)
+	addr := fs.String("addr", syncServerAddr, "address to listen on")
+	log := fs.String("log", syncServerLog, "file keeping every review the server was sent")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	server, err := newSyncServer(*log)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "Serving %d reviews from %s on %s\n", len(server.reviews), *log, *addr)
+	return http.ListenAndServe(*addr, server)
+}
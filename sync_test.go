@@ -0,0 +1,163 @@
+package main
+
+import (
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// syncDevice is the part of a device's state that sync touches: its review
+// journal, how far it synced with the server and its scores.
+type syncDevice struct {
+	Journal    []Review
+	State      syncState
+	Highlights HighlightDatabase
+}
+
+func newSyncDevice(journal ...Review) *syncDevice {
+	d := &syncDevice{
+		Journal: journal,
+		Highlights: HighlightDatabase{
+			Highlights:      []Highlight{{ID: "a"}},
+			UnmatchedScores: make(map[string]Score),
+		},
+	}
+	for _, r := range journal {
+		applyReview(d.Highlights, r)
+	}
+	return d
+}
+
+// sync runs one sync and appends the pulled reviews to the journal, the way
+// saveScores does after cmdSync.
+func (d *syncDevice) sync(t *testing.T, ts *httptest.Server) int {
+	t.Helper()
+	pendingReviews = nil
+	state, fresh, err := syncReviews(ts.Client(), ts.URL, d.Journal, d.State, d.Highlights)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Journal = append(d.Journal, pendingReviews...)
+	pendingReviews = nil
+	d.State = state
+	return fresh
+}
+
+func (d *syncDevice) count(id string) int {
+	if h := d.Highlights.FindHighlight(id); h != nil {
+		return h.Score.Count
+	}
+	return d.Highlights.UnmatchedScores[id].Count
+}
+
+func TestSyncReviews(t *testing.T) {
+	at := time.Date(2026, 10, 1, 9, 0, 0, 123456789, time.UTC)
+	r1 := Review{ID: "a", Time: at, Ratio: 1}
+	r2 := Review{ID: "b", Time: at.Add(time.Minute), Ratio: 0.5}
+	r3 := Review{ID: "a", Time: at.Add(time.Hour), Ratio: 0.25}
+
+	log := filepath.Join(t.TempDir(), syncServerLog)
+	server, err := newSyncServer(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	laptop := newSyncDevice(r1, r2)
+	desktop := newSyncDevice(r3)
+
+	if fresh := laptop.sync(t, ts); fresh != 0 {
+		t.Errorf("laptop pulled %d new reviews from its own push, want 0", fresh)
+	}
+	if laptop.State != (syncState{Pushed: 2, Pulled: 2}) {
+		t.Errorf("laptop state %+v, want pushed 2 pulled 2", laptop.State)
+	}
+
+	if fresh := desktop.sync(t, ts); fresh != 2 {
+		t.Errorf("desktop pulled %d new reviews, want 2", fresh)
+	}
+	if desktop.State != (syncState{Pushed: 3, Pulled: 3}) {
+		t.Errorf("desktop state %+v, want pushed 3 pulled 3", desktop.State)
+	}
+	if desktop.count("a") != 2 || desktop.count("b") != 1 {
+		t.Errorf("desktop counts a=%d b=%d, want 2 and 1", desktop.count("a"), desktop.count("b"))
+	}
+
+	if fresh := laptop.sync(t, ts); fresh != 1 {
+		t.Errorf("laptop pulled %d new reviews, want 1", fresh)
+	}
+	if laptop.State != (syncState{Pushed: 3, Pulled: 3}) {
+		t.Errorf("laptop state %+v, want pushed 3 pulled 3", laptop.State)
+	}
+
+	// Syncing again changes nothing on either side.
+	for _, d := range []*syncDevice{laptop, desktop} {
+		if fresh := d.sync(t, ts); fresh != 0 {
+			t.Errorf("second sync pulled %d new reviews, want 0", fresh)
+		}
+		if d.count("a") != 2 || d.count("b") != 1 || len(d.Journal) != 3 {
+			t.Errorf("after a second sync: a=%d b=%d journal=%d, want 2, 1 and 3", d.count("a"), d.count("b"), len(d.Journal))
+		}
+		if d.State != (syncState{Pushed: 3, Pulled: 3}) {
+			t.Errorf("state after a second sync %+v, want pushed 3 pulled 3", d.State)
+		}
+	}
+
+	// A review pushed twice is kept once, also across a server restart.
+	restarted, err := newSyncServer(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(restarted.reviews) != 3 {
+		t.Errorf("server log has %d reviews, want 3", len(restarted.reviews))
+	}
+}
+
+// TestSyncReviewOrder has devices pull the same reviews in different orders,
+// one of them also holding one review already, and expects the same score.
+func TestSyncReviewOrder(t *testing.T) {
+	at := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+	reviews := []Review{
+		{ID: "a", Time: at, Ratio: 1},
+		{ID: "a", Time: at.Add(time.Minute), Ratio: 0.25},
+		{ID: "a", Time: at.Add(time.Hour), Ratio: 0.5},
+		{ID: "b", Time: at.Add(time.Hour), Ratio: 0.75},
+	}
+	orders := [][]int{{0, 1, 2, 3}, {3, 2, 1, 0}, {1, 3, 0, 2}, {2, 0, 3, 1}}
+
+	var expected map[string]string
+	for _, order := range orders {
+		for _, local := range []int{-1, 1} {
+			ts := httptest.NewServer(&syncServer{Log: filepath.Join(t.TempDir(), syncServerLog), keys: make(map[string]bool)})
+			var arrival []Review
+			for _, k := range order {
+				arrival = append(arrival, reviews[k])
+			}
+			newSyncDevice(arrival...).sync(t, ts)
+
+			// Every device starts from the same reviews made before it kept
+			// a journal.
+			device := newSyncDevice()
+			device.Highlights.Highlights[0].Score = Score{Sum: 4, Count: 2}
+			device.Highlights.UnmatchedScores["b"] = Score{Sum: 4, Count: 2}
+			if local >= 0 {
+				device.Journal = append(device.Journal, reviews[local])
+				applyReview(device.Highlights, reviews[local])
+			}
+			device.sync(t, ts)
+			ts.Close()
+
+			actual := map[string]string{
+				"a": formatScore("a", device.Highlights.FindHighlight("a").Score),
+				"b": formatScore("b", device.Highlights.UnmatchedScores["b"]),
+			}
+			if expected == nil {
+				expected = actual
+			} else if actual["a"] != expected["a"] || actual["b"] != expected["b"] {
+				t.Errorf("order %v with local review %d: scores %q, want %q", order, local, actual, expected)
+			}
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type ParsedToken struct {
+	Content     string
+	RealContent string
+	Start       int
+	End         int
+}
+
+type Contraction struct {
+	Prefix string
+	Suffix string
+}
+
+type TokenizerState struct {
+	Input  []rune
+	Tokens []ParsedToken
+	Number bool
+	URL    bool
+}
+
+// MergeRule is offered every token completed at a separator. It returns true
+// when it has consumed the token, usually by extending the last one in
+// state.Tokens.
+type MergeRule func(state *TokenizerState, token ParsedToken) bool
+
+type Tokenizer struct {
+	Whitespace     map[rune]bool
+	Separators     map[rune]bool
+	Accompanies    map[rune]bool
+	Digits         map[rune]bool
+	NumberRunes    map[rune]bool
+	NumberJoiners  map[rune]bool
+	Ignored        map[rune]bool
+	NumberSuffixes []string
+	Rules          []MergeRule
+}
+
+var defaultTokenizer = NewTokenizer()
+
+func NewTokenizer() *Tokenizer {
+	return &Tokenizer{
+		Whitespace: runeSet(' ', '\t', '\r', '\n'),
+		Separators: runeSet(
+			'.', '"', ',', ':', '\n', '\r', '\t', ' ', ';', '\'', '-', '_', '=', '+', '#',
+			'(', ')', '[', ']', '{', '}', '!', '‘', '’', '–',
+		),
+		Accompanies:    runeSet('\'', '"', '‘', '’', '–'),
+		Digits:         runeSet('0', '1', '2', '3', '4', '5', '6', '7', '8', '9'),
+		NumberRunes:    runeSet('.', '-', '+', '%', ',', ':'),
+		NumberJoiners:  runeSet(',', '.', ':'),
+		Ignored:        runeSet('*'),
+		NumberSuffixes: []string{"am", "pm", "bc", "ad"},
+		Rules: []MergeRule{
+			EraRule("ad", "bc"),
+			URLRule([]string{"www", "http", "https"}, runeSet('.', '/', '-', ':')),
+			ContractionRule(
+				Contraction{Prefix: "don", Suffix: "t"},
+				Contraction{Prefix: "you", Suffix: "ve"},
+				Contraction{Prefix: "you", Suffix: "ll"},
+			),
+		},
+	}
+}
+
+func (t *Tokenizer) Tokenize(str string) []ParsedToken {
+	state := &TokenizerState{
+		Input:  []rune(str),
+		Number: true,
+	}
+	input := state.Input
+
+	var current []rune
+	var tokenStart int
+
+	for i := 0; i < len(input); i++ {
+		r := input[i]
+
+		if len(current) == 0 && t.Separators[r] {
+			continue
+		}
+		if t.Accompanies[r] && i+1 < len(input) && ((i > 0 && (input[i-1] == 'o' || input[i-1] == 'O')) || input[i+1] == 's') {
+			if len(current) == 0 {
+				tokenStart = i
+			}
+			current = append(current, '\'', input[i+1])
+			i = i + 1
+			continue
+		}
+		if t.NumberJoiners[r] && len(current) > 0 && t.Digits[current[len(current)-1]] && i+1 < len(input) && t.Digits[input[i+1]] {
+			continue
+		}
+		if t.Ignored[r] {
+			continue
+		}
+
+		if t.Separators[r] {
+			if state.Number {
+				if suffix, end := t.numberSuffix(input, i); suffix != nil {
+					current = append(current, suffix...)
+					i = end
+				}
+			}
+
+			t.finish(state, ParsedToken{
+				Content:     strings.ToLower(string(current)),
+				Start:       tokenStart,
+				End:         i,
+				RealContent: string(input[tokenStart:i]),
+			})
+
+			current = nil
+			state.Number = true
+			continue
+		}
+
+		if len(current) == 0 {
+			tokenStart = i
+		}
+		if !t.Digits[r] && !t.NumberRunes[r] {
+			state.Number = false
+		}
+		current = append(current, r)
+	}
+
+	// The trailing token never passes through the merge rules. Changing that
+	// would change generateID for existing highlights and orphan their scores.
+	if len(current) > 0 {
+		state.Tokens = append(state.Tokens, ParsedToken{
+			Content:     strings.ToLower(string(current)),
+			Start:       tokenStart,
+			End:         len(input),
+			RealContent: string(input[tokenStart:]),
+		})
+	}
+
+	return state.Tokens
+}
+
+func (t *Tokenizer) finish(state *TokenizerState, token ParsedToken) {
+	for _, rule := range t.Rules {
+		if rule(state, token) {
+			return
+		}
+	}
+	state.Tokens = append(state.Tokens, token)
+	state.URL = false
+}
+
+func (t *Tokenizer) numberSuffix(input []rune, i int) ([]rune, int) {
+	j := i
+	for j < len(input) && t.Whitespace[input[j]] {
+		j = j + 1
+	}
+	for _, suffix := range t.NumberSuffixes {
+		rs := []rune(suffix)
+		if j+len(rs) <= len(input) && strings.EqualFold(string(input[j:j+len(rs)]), suffix) {
+			return input[j : j+len(rs)], j + len(rs)
+		}
+	}
+	return nil, i
+}
+
+func EraRule(eras ...string) MergeRule {
+	return func(state *TokenizerState, token ParsedToken) bool {
+		if !state.Number || len(state.Tokens) == 0 {
+			return false
+		}
+		last := state.Tokens[len(state.Tokens)-1]
+		for _, era := range eras {
+			if last.Content == era {
+				state.mergeLast(fmt.Sprintf("%s %s", last.Content, token.Content), token.End)
+				state.URL = false
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func URLRule(prefixes []string, joiners map[rune]bool) MergeRule {
+	return func(state *TokenizerState, token ParsedToken) bool {
+		if state.URL && token.Start > 0 && joiners[state.Input[token.Start-1]] {
+			last := state.Tokens[len(state.Tokens)-1]
+			state.mergeLast(fmt.Sprintf("%s.%s", last.Content, token.Content), token.End)
+			return true
+		}
+		for _, prefix := range prefixes {
+			if token.RealContent == prefix {
+				state.Tokens = append(state.Tokens, token)
+				state.URL = true
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func ContractionRule(contractions ...Contraction) MergeRule {
+	return func(state *TokenizerState, token ParsedToken) bool {
+		if len(state.Tokens) == 0 || token.Start == 0 || state.Input[token.Start-1] != '\'' {
+			return false
+		}
+		last := state.Tokens[len(state.Tokens)-1]
+		for _, c := range contractions {
+			if last.Content == c.Prefix && token.Content == c.Suffix {
+				state.mergeLast(fmt.Sprintf("%s'%s", last.Content, token.Content), token.End)
+				state.URL = false
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func (state *TokenizerState) mergeLast(content string, end int) {
+	last := state.Tokens[len(state.Tokens)-1]
+	state.Tokens[len(state.Tokens)-1] = ParsedToken{
+		Content:     content,
+		Start:       last.Start,
+		End:         end,
+		RealContent: string(state.Input[last.Start:end]),
+	}
+}
+
+func CheckSpans(str string, tokens []ParsedToken) error {
+	input := []rune(str)
+	previousEnd := 0
+	for i, token := range tokens {
+		if token.Start < previousEnd || token.Start > token.End || token.End > len(input) {
+			return fmt.Errorf("token %d (%q) has span [%d, %d) outside of input with %d runes", i, token.Content, token.Start, token.End, len(input))
+		}
+		if string(input[token.Start:token.End]) != token.RealContent {
+			return fmt.Errorf("token %d (%q) real content %q does not match its span [%d, %d)", i, token.Content, token.RealContent, token.Start, token.End)
+		}
+		previousEnd = token.End
+	}
+	return nil
+}
+
+func runeSet(rs ...rune) map[rune]bool {
+	result := make(map[rune]bool)
+	for _, r := range rs {
+		result[r] = true
+	}
+	return result
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/arcana261/lifeinuk/sliceutils"
+)
+
+func TestTokenizeGolden(t *testing.T) {
+	inputs, err := filepath.Glob(filepath.Join("testdata", "tokenizer", "*.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inputs) == 0 {
+		t.Fatal("no inputs in testdata/tokenizer")
+	}
+	for _, input := range inputs {
+		t.Run(filepath.Base(input), func(t *testing.T) {
+			b, err := os.ReadFile(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			expected, err := os.ReadFile(strings.TrimSuffix(input, ".txt") + ".golden")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tokens := defaultTokenizer.Tokenize(string(b))
+			if err := CheckSpans(string(b), tokens); err != nil {
+				t.Fatal(err)
+			}
+			if actual := formatGolden(tokens); actual != string(expected) {
+				t.Errorf("tokens differ from the golden file, run \"tokenize -check testdata/tokenizer -update\" if the change is intended\nwant:\n%s\ngot:\n%s", expected, actual)
+			}
+		})
+	}
+}
+
+func FuzzTokenize(f *testing.F) {
+	for _, fragment := range fuzzFragments {
+		f.Add(fragment)
+	}
+	inputs, _ := filepath.Glob(filepath.Join("testdata", "tokenizer", "*.txt"))
+	for _, input := range inputs {
+		if b, err := os.ReadFile(input); err == nil {
+			f.Add(string(b))
+		}
+	}
+	f.Fuzz(func(t *testing.T, input string) {
+		if err := CheckSpans(input, defaultTokenizer.Tokenize(input)); err != nil {
+			t.Errorf("input %q: %v", input, err)
+		}
+	})
+}
+
+func TestMergePhrases(t *testing.T) {
+	entry := "The House of\nCommons met, the house of lords did not. House of Commons."
+	tokens := defaultTokenizer.Tokenize(entry)
+	phrases := newPhraseSet([][]string{{"house", "of"}, {"house", "of", "commons"}})
+	merged := mergePhrases(entry, tokens, phrases)
+
+	var real []string
+	for _, token := range merged {
+		if strings.Contains(token.Content, " ") {
+			real = append(real, token.Content+"="+token.RealContent)
+		}
+	}
+	expected := []string{"house of commons=House of Commons", "house of=house of", "house of commons=House of Commons"}
+	if strings.Join(real, "|") != strings.Join(expected, "|") {
+		t.Errorf("merged phrases %q, want %q", real, expected)
+	}
+	for _, token := range merged {
+		if rs := []rune(entry); strings.Join(strings.Fields(string(rs[token.Start:token.End])), " ") != token.RealContent {
+			t.Errorf("token %q spans %q", token.RealContent, string(rs[token.Start:token.End]))
+		}
+	}
+
+	content := func(x ParsedToken) string {
+		return x.Content
+	}
+	if generateID(sliceutils.MapFunc(merged, content)) != generateID(sliceutils.MapFunc(tokens, content)) {
+		t.Errorf("merging phrases changed the ID")
+	}
+}
+
+func TestDetectPhrases(t *testing.T) {
+	entries := []string{
+		"The Magna Carta was signed in 1215.",
+		"The Magna Carta limited the king.",
+		"Henry VIII had six wives.",
+	}
+	phrases := detectPhrases(entries, sliceutils.MapFunc(entries, defaultTokenizer.Tokenize))
+	var found []string
+	for _, phrase := range phrases {
+		found = append(found, strings.Join(phrase, " "))
+	}
+	if strings.Join(found, "|") != "magna carta" {
+		t.Errorf("detected phrases %q, want only \"magna carta\", which appears twice", found)
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/arcana261/lifeinuk/sliceutils"
+)
+
+const (
+	tokenClassYear   = "year"
+	tokenClassNumber = "number"
+	tokenClassName   = "name"
+)
+
+type perturbation struct {
+	Index      int
+	Substitute int
+}
+
+func tokenClass(content string, realContent string) string {
+	digits := content
+	for _, era := range []string{"ad ", "bc "} {
+		digits = strings.TrimPrefix(digits, era)
+	}
+	for _, era := range []string{"ad", "bc"} {
+		digits = strings.TrimSuffix(digits, era)
+	}
+	if digits != "" && strings.IndexFunc(digits, func(r rune) bool { return !unicode.IsDigit(r) }) < 0 {
+		if digits != content || (len(digits) >= 3 && len(digits) <= 4) {
+			return tokenClassYear
+		}
+		return tokenClassNumber
+	}
+
+	rs := []rune(realContent)
+	if len(rs) > 0 && unicode.IsUpper(rs[0]) && !phraseStopWords[content] {
+		return tokenClassName
+	}
+	return ""
+}
+
+func highlightTokenClass(highlights HighlightDatabase, h *Highlight, i int) string {
+	content := []rune(h.Content)
+	class := tokenClass(highlights.TokenMap[h.Tokens[i]].Content, string(content[h.TokenStarts[i]:h.TokenEnds[i]]))
+	if class == tokenClassName && (i == 0 || strings.ContainsAny(string(content[h.TokenEnds[i-1]:h.TokenStarts[i]]), ".!?")) {
+		return ""
+	}
+	return class
+}
+
+func classTokens(highlights HighlightDatabase, class string) []int {
+	var result []int
+	for k := range highlights.Highlights {
+		h := &highlights.Highlights[k]
+		for i := 0; i < len(h.Tokens); i++ {
+			if !highlights.TokenMap[h.Tokens[i]].SkipPuzzle && highlightTokenClass(highlights, h, i) == class {
+				result = append(result, h.Tokens[i])
+			}
+		}
+	}
+	return sliceutils.UniqueSorted(sliceutils.Sort(result))
+}
+
+func perturbHighlight(highlights HighlightDatabase, h *Highlight) (perturbation, bool) {
+	var keyPositions []int
+	var otherPositions []int
+	for i := 1; i < len(h.Tokens); i++ {
+		if highlights.TokenMap[h.Tokens[i]].SkipPuzzle {
+			continue
+		}
+		if highlightTokenClass(highlights, h, i) != "" {
+			keyPositions = append(keyPositions, i)
+		} else {
+			otherPositions = append(otherPositions, i)
+		}
+	}
+	sliceutils.Permutate(highlights.Rand, keyPositions)
+	sliceutils.Permutate(highlights.Rand, otherPositions)
+
+	for _, i := range append(keyPositions, otherPositions...) {
+		current := highlights.TokenMap[h.Tokens[i]]
+		class := highlightTokenClass(highlights, h, i)
+
+		var candidates []int
+		if class != "" {
+			candidates = sliceutils.Remove(classTokens(highlights, class), current.ID)
+		}
+		if len(candidates) == 0 {
+			candidates = highlights.TokenMap[h.Tokens[i-1]].NominateNextTokens(highlights, 1, current.Content)
+		}
+		if len(candidates) == 0 {
+			continue
+		}
+
+		return perturbation{
+			Index:      i,
+			Substitute: candidates[highlights.Rand.Intn(len(candidates))],
+		}, true
+	}
+
+	return perturbation{}, false
+}
+
+func trueFalseCard(highlights HighlightDatabase) error {
+	h := highlights.PickHighlight()
+	if h == nil {
+		return nil
+	}
+	hContent := []rune(h.Content)
+
+	var p perturbation
+	altered := false
+	if highlights.Rand.Intn(2) == 0 {
+		p, altered = perturbHighlight(highlights, h)
+	}
+
+	statement := h.Content
+	var substitute string
+	if altered {
+		original := string(hContent[h.TokenStarts[p.Index]:h.TokenEnds[p.Index]])
+		substitute = highlights.TokenMap[p.Substitute].RealContent
+		if rs := []rune(original); len(rs) > 0 && unicode.IsUpper(rs[0]) {
+			substitute = capitalize(substitute)
+		}
+		statement = string(hContent[:h.TokenStarts[p.Index]]) + substitute + string(hContent[h.TokenEnds[p.Index]:])
+	}
+
+	var answer string
+	for answer != "t" && answer != "f" {
+		printContent(fmt.Sprintf("> %s", statement))
+		fmt.Fprintf(stdout, "  T. True\n")
+		fmt.Fprintf(stdout, "  F. False\n")
+		fmt.Fprintf(stdout, "  Q. Quit\n")
+		fmt.Fprintf(stdout, "\n")
+
+		key, err := readOne()
+		if err != nil {
+			return err
+		}
+		answer = strings.ToLower(key)
+		if answer == "q" {
+			return nil
+		}
+	}
+
+	if (answer == "t") != altered {
+		fmt.Fprintf(stdout, "%sCORRECT!%s\n", colorGreen, colorNone)
+		h.Record(1, 0)
+	} else {
+		fmt.Fprintf(stdout, "%sWRONG!%s\n", colorRed, colorNone)
+		h.Record(0, 1)
+	}
+
+	if altered {
+		var lineToPrint bytes.Buffer
+		lineToPrint.WriteString(string(hContent[:h.TokenStarts[p.Index]]))
+		lineToPrint.WriteString(colorRed)
+		lineToPrint.WriteString(substitute)
+		lineToPrint.WriteString(colorNone)
+		lineToPrint.WriteString(" ")
+		lineToPrint.WriteString(colorGreen)
+		lineToPrint.WriteString(string(hContent[h.TokenStarts[p.Index]:h.TokenEnds[p.Index]]))
+		lineToPrint.WriteString(colorNone)
+		lineToPrint.WriteString(string(hContent[h.TokenEnds[p.Index]:]))
+		printContent(lineToPrint.String())
+	} else {
+		printContent(h.Content)
+	}
+
+	return saveScores(highlights)
+}
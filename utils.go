@@ -6,17 +6,18 @@ import (
 	"strings"
 )
 
-func copyFile(src string, dst string) {
+func copyFile(src string, dst string) error {
 	// Read all content of src to data, may cause OOM for a large file.
 	data, err := ioutil.ReadFile(src)
 	if err != nil {
-		panic(err)
+		return err
 	}
 	// Write data to dst
 	err = ioutil.WriteFile(dst, data, 0644)
 	if err != nil {
-		panic(err)
+		return &WriteError{Path: dst, Err: err}
 	}
+	return nil
 }
 
 func fileExists(filename string) bool {
@@ -58,6 +59,14 @@ func fixAlignment(str string, width int) string {
 	return strings.Join(result, "\n")
 }
 
+func capitalize(txt string) string {
+	if len(txt) == 0 {
+		return txt
+	}
+	rtxt := []rune(txt)
+	return strings.ToUpper(string(rtxt[:1])) + string(rtxt[1:])
+}
+
 func CompareFloat64(x, y float64) int {
 	if x+1e-5 < y {
 		return -1
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"unicode"
+
+	"github.com/arcana261/lifeinuk/sliceutils"
+)
+
+const (
+	worksheetHTML     = "html"
+	worksheetMarkdown = "markdown"
+	worksheetCards    = 20
+	worksheetBlanks   = 3
+	worksheetBlank    = "__________"
+)
+
+// clozePart is either plain text or, when Blank is positive, the numbered
+// blank hiding Text.
+type clozePart struct {
+	Text  string
+	Blank int
+}
+
+type clozeCard struct {
+	Question string
+	Parts    []clozePart
+}
+
+// weakestHighlights picks up to n highlights that are not suspended, lowest
+// average first.
+func weakestHighlights(highlights HighlightDatabase, n int) []*Highlight {
+	items := sliceutils.MapFunc(sliceutils.Range(0, len(highlights.Highlights)), func(i int) *Highlight {
+		return &highlights.Highlights[i]
+	})
+	items = sliceutils.FilterFunc(items, func(h *Highlight) bool {
+		return !h.Score.HasFlag(flagSuspended)
+	})
+	items = sliceutils.SortFunc(items, func(a, b *Highlight) int {
+		if c := CompareFloat64(a.Score.WeightedAverage(), b.Score.WeightedAverage()); c != 0 {
+			return c
+		}
+		return a.Index - b.Index
+	})
+	return items[:min(n, len(items))]
+}
+
+// clozeCards blanks up to blanks of the most informative tokens the fill game
+// would quiz in each card, numbering the blanks across all cards;
+// answers[k-1] is the text of blank k.
+func clozeCards(highlights HighlightDatabase, chosen []*Highlight, blanks int) ([]clozeCard, []string) {
+	var cards []clozeCard
+	var answers []string
+	for _, h := range chosen {
+		var picked []int
+		for _, i := range informativeTokens(highlights, h, fillFirstToken) {
+			if len(picked) == blanks {
+				break
+			}
+			// A word blanked once is enough of a hint for its repeats.
+			if !sliceutils.ContainsFunc(picked, func(k int) bool { return h.Tokens[k] == h.Tokens[i] }) {
+				picked = append(picked, i)
+			}
+		}
+
+		content := []rune(h.Content)
+		card := clozeCard{Question: h.Question}
+		last := 0
+		for _, i := range sliceutils.Sort(picked) {
+			card.Parts = append(card.Parts, clozePart{Text: string(content[last:h.TokenStarts[i]])})
+			answers = append(answers, string(content[h.TokenStarts[i]:h.TokenEnds[i]]))
+			card.Parts = append(card.Parts, clozePart{Text: answers[len(answers)-1], Blank: len(answers)})
+			last = h.TokenEnds[i]
+		}
+		card.Parts = append(card.Parts, clozePart{Text: string(content[last:])})
+		cards = append(cards, card)
+	}
+	return cards, answers
+}
+
+// collapseSpace turns every run of whitespace into a single space, keeping
+// the spaces around a blank.
+func collapseSpace(s string) string {
+	var result strings.Builder
+	space := false
+	for _, r := range s {
+		if unicode.IsSpace(r) {
+			if !space {
+				result.WriteByte(' ')
+			}
+			space = true
+			continue
+		}
+		space = false
+		result.WriteRune(r)
+	}
+	return result.String()
+}
+
+func writeMarkdownWorksheet(w io.Writer, cards []clozeCard, answers []string) error {
+	var buff bytes.Buffer
+	buff.WriteString("# Worksheet\n")
+	for i, card := range cards {
+		fmt.Fprintf(&buff, "\n%d. ", i+1)
+		if card.Question != "" {
+			fmt.Fprintf(&buff, "**%s** ", strings.Join(strings.Fields(card.Question), " "))
+		}
+		var line strings.Builder
+		for _, part := range card.Parts {
+			if part.Blank > 0 {
+				fmt.Fprintf(&line, "**(%d)** %s", part.Blank, strings.ReplaceAll(worksheetBlank, "_", "\\_"))
+			} else {
+				line.WriteString(collapseSpace(part.Text))
+			}
+		}
+		buff.WriteString(strings.TrimSpace(line.String()))
+		buff.WriteString("\n")
+	}
+
+	buff.WriteString("\n<div style=\"page-break-before: always\"></div>\n\n## Answer key\n\n")
+	for k, answer := range answers {
+		fmt.Fprintf(&buff, "%d. %s\n", k+1, collapseSpace(answer))
+	}
+	_, err := w.Write(buff.Bytes())
+	return err
+}
+
+func writeHTMLWorksheet(w io.Writer, cards []clozeCard, answers []string) error {
+	var buff bytes.Buffer
+	buff.WriteString(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Worksheet</title>
+<style>
+body { font-family: serif; line-height: 2.2; max-width: 45em; margin: auto; }
+.blank { white-space: nowrap; }
+.blank sup { font-weight: bold; }
+.answers { page-break-before: always; line-height: 1.5; }
+</style>
+</head>
+<body>
+<h1>Worksheet</h1>
+<ol>
+`)
+	for _, card := range cards {
+		buff.WriteString("<li>")
+		if card.Question != "" {
+			fmt.Fprintf(&buff, "<strong>%s</strong> ", html.EscapeString(card.Question))
+		}
+		for _, part := range card.Parts {
+			if part.Blank > 0 {
+				fmt.Fprintf(&buff, `<span class="blank"><sup>%d</sup>%s</span>`, part.Blank, worksheetBlank)
+			} else {
+				buff.WriteString(html.EscapeString(collapseSpace(part.Text)))
+			}
+		}
+		buff.WriteString("</li>\n")
+	}
+	buff.WriteString("</ol>\n<div class=\"answers\">\n<h2>Answer key</h2>\n<ol>\n")
+	for _, answer := range answers {
+		fmt.Fprintf(&buff, "<li>%s</li>\n", html.EscapeString(collapseSpace(answer)))
+	}
+	buff.WriteString("</ol>\n</div>\n</body>\n</html>\n")
+	_, err := w.Write(buff.Bytes())
+	return err
+}
+
+func cmdWorksheet(args []string) error {
+	fs := flag.NewFlagSet("worksheet", flag.ContinueOnError)
+	n := fs.Int("n", worksheetCards, "number of highlights on the sheet, weakest first")
+	blanks := fs.Int("blanks", worksheetBlanks, "number of words blanked in each highlight")
+	format := fs.String("format", worksheetHTML, "output format: html or markdown")
+	output := fs.String("o", "", "write to this file instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var write func(io.Writer, []clozeCard, []string) error
+	switch *format {
+	case worksheetHTML:
+		write = writeHTMLWorksheet
+	case worksheetMarkdown:
+		write = writeMarkdownWorksheet
+	default:
+		return fmt.Errorf("unknown format %q, use %s or %s", *format, worksheetHTML, worksheetMarkdown)
+	}
+
+	highlights, err := loadHighlights()
+	if err != nil {
+		return err
+	}
+	cards, answers := clozeCards(highlights, weakestHighlights(highlights, *n), *blanks)
+
+	if *output == "" {
+		return write(stdout, cards, answers)
+	}
+	var buff bytes.Buffer
+	if err := write(&buff, cards, answers); err != nil {
+		return err
+	}
+	return writeFileAtomic(*output, buff.Bytes())
+}